@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ── System actions ────────────────────────────────────────────────────────
+
+// action is something bf can do to the machine for a Brewfile entry.
+type action int
+
+const (
+	actInstall action = iota
+	actUpgrade
+	actUninstall
+)
+
+func (a action) String() string {
+	return [...]string{"install", "upgrade", "uninstall"}[a]
+}
+
+// actionArgv builds the command that applies a to one entry, or ok=false
+// when the entry's tool has no such operation.
+func actionArgv(a action, e *entry) (argv []string, ok bool) {
+	switch e.Kind {
+	case kindBrew, kindCask:
+		argv = []string{"brew", a.String()}
+		if e.Kind == kindCask {
+			argv = append(argv, "--cask")
+		}
+		return append(argv, e.Name), true
+	case kindTap:
+		switch a {
+		case actInstall:
+			argv = []string{"brew", "tap", e.Name}
+			if len(e.Args) > 0 {
+				if url, err := strconv.Unquote(e.Args[0]); err == nil {
+					argv = append(argv, url)
+				}
+			}
+			return argv, true
+		case actUninstall:
+			return []string{"brew", "untap", e.Name}, true
+		}
+	case kindMas:
+		if id, ok := e.Option("id"); ok {
+			return []string{"mas", a.String(), id}, true
+		}
+	case kindVSCode:
+		switch a {
+		case actInstall:
+			return []string{"code", "--install-extension", e.Name}, true
+		case actUpgrade:
+			return []string{"code", "--install-extension", e.Name, "--force"}, true
+		case actUninstall:
+			return []string{"code", "--uninstall-extension", e.Name}, true
+		}
+	case kindWhalebrew:
+		switch a {
+		case actInstall:
+			return []string{"whalebrew", "install", e.Name}, true
+		case actUninstall:
+			return []string{"whalebrew", "uninstall", e.Name}, true
+		}
+	}
+	return nil, false
+}
+
+// actionPlan groups the commands for a over targets, batching formulae and
+// casks into one brew invocation each so brew resolves them together.
+// skipped names entries whose tool can't do a.
+func actionPlan(a action, targets []*entry) (plan [][]string, skipped []string) {
+	batch := map[pkgKind]int{} // kind → index into plan
+	for _, e := range targets {
+		argv, ok := actionArgv(a, e)
+		if !ok {
+			skipped = append(skipped, e.Name)
+			continue
+		}
+		if e.Kind == kindBrew || e.Kind == kindCask {
+			if i, seen := batch[e.Kind]; seen {
+				plan[i] = append(plan[i], e.Name)
+				continue
+			}
+			batch[e.Kind] = len(plan)
+		}
+		plan = append(plan, argv)
+	}
+	return plan, skipped
+}
+
+// actionDoneMsg reports one finished command from a running plan.
+type actionDoneMsg struct {
+	argv []string
+	err  error
+}
+
+// runArgv hands the terminal to argv, like mrk-menu's runCmd.
+func runArgv(argv []string) tea.Cmd {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return actionDoneMsg{argv: argv, err: err}
+	})
+}
+
+// startAction runs (or, in dry-run mode, just shows) a for targets.
+func (m model) startAction(a action, targets []*entry) (model, tea.Cmd) {
+	m.state = stateNormal
+	if len(targets) == 0 {
+		m.flash = fmt.Sprintf("nothing to %s", a)
+		return m, nil
+	}
+	plan, skipped := actionPlan(a, targets)
+	if len(plan) == 0 {
+		m.flash = fmt.Sprintf("%s not available for %s", a, strings.Join(skipped, ", "))
+		return m, nil
+	}
+	var cmds []string
+	for _, argv := range plan {
+		cmds = append(cmds, strings.Join(argv, " "))
+	}
+	if m.dryRun {
+		m.flash = "dry run: " + strings.Join(cmds, " && ")
+		return m, nil
+	}
+	m.actDesc = fmt.Sprintf("%s \"%s\"", a, targets[0].Name)
+	if len(targets) > 1 {
+		m.actDesc = fmt.Sprintf("%s %d entries", a, len(targets))
+	}
+	m.actQueue = plan[1:]
+	m.actFailed = nil
+	return m, runArgv(plan[0])
+}
+
+// actionDone records a finished command and starts the next one; after the
+// last, the installed badges are refreshed.
+func (m model) actionDone(msg actionDoneMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(msg.err, &exitErr) {
+			m.actFailed = append(m.actFailed, fmt.Sprintf("%s exited %d", strings.Join(msg.argv[:2], " "), exitErr.ExitCode()))
+		} else {
+			m.actFailed = append(m.actFailed, fmt.Sprintf("%s failed: %v", msg.argv[0], msg.err))
+		}
+	}
+	if len(m.actQueue) > 0 {
+		next := m.actQueue[0]
+		m.actQueue = m.actQueue[1:]
+		return m, runArgv(next)
+	}
+	if len(m.actFailed) > 0 {
+		m.flash = strings.Join(m.actFailed, "; ")
+	} else {
+		m.flash = "done: " + m.actDesc
+	}
+	var cmd tea.Cmd
+	if m.brew != nil {
+		cmd = fetchStatus(m.brew)
+	}
+	return m, tea.Batch(tea.ClearScreen, cmd)
+}
+
+// actionTargets is what an action key applies to: the marked entries, or
+// the entry under the cursor when nothing is marked.
+func (m model) actionTargets() []*entry {
+	if marked := m.markedEntries(); len(marked) > 0 {
+		return marked
+	}
+	if e := m.currentEntry(); e != nil {
+		return []*entry{e}
+	}
+	return nil
+}
+
+// confirmTargets is what the confirmation prompt is about, and the state a
+// cancel returns to.
+func (m model) confirmTargets() ([]*entry, viewState) {
+	if m.pendingTargets != nil {
+		return m.pendingTargets, statePrune
+	}
+	return m.actionTargets(), stateNormal
+}
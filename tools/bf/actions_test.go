@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	bfile "mrk-brewfile"
+)
+
+func TestActionPlan(t *testing.T) {
+	parse := func(line string) *entry {
+		e, ok := bfile.ParseEntry(line)
+		if !ok {
+			t.Fatalf("ParseEntry(%q) failed", line)
+		}
+		return e
+	}
+	targets := []*entry{
+		parse(`brew "ffmpeg"`),
+		parse(`cask "vlc", greedy: true`),
+		parse(`brew "yt-dlp"`),
+		parse(`tap "user/repo", "https://example.com/repo.git"`),
+		parse(`mas "Xcode", id: 497799835`),
+		parse(`whalebrew "whalebrew/wget"`),
+	}
+
+	plan, skipped := actionPlan(actInstall, targets)
+	want := [][]string{
+		{"brew", "install", "ffmpeg", "yt-dlp"},
+		{"brew", "install", "--cask", "vlc"},
+		{"brew", "tap", "user/repo", "https://example.com/repo.git"},
+		{"mas", "install", "497799835"},
+		{"whalebrew", "install", "whalebrew/wget"},
+	}
+	if !reflect.DeepEqual(plan, want) || skipped != nil {
+		t.Errorf("install plan = %q, skipped %q", plan, skipped)
+	}
+
+	plan, skipped = actionPlan(actUpgrade, targets)
+	if len(plan) != 3 || !reflect.DeepEqual(skipped, []string{"user/repo", "whalebrew/wget"}) {
+		t.Errorf("upgrade plan = %q, skipped %q", plan, skipped)
+	}
+}
+
+func TestActionsDryRunAndConfirm(t *testing.T) {
+	m := fixtureModel(t)
+	m.dryRun = true
+	m = press(m, "j", "l") // ffmpeg
+
+	m = press(m, "i")
+	if m.flash != "dry run: brew install ffmpeg" {
+		t.Errorf("install flash = %q", m.flash)
+	}
+	m = press(m, "x")
+	if m.state != stateActionConfirm {
+		t.Fatalf("x: state = %v", m.state)
+	}
+	if m = press(m, "n"); m.state != stateNormal || m.flash != "cancelled" {
+		t.Errorf("cancel: state=%v flash=%q", m.state, m.flash)
+	}
+	m = press(m, "x", "y")
+	if m.flash != "dry run: brew uninstall ffmpeg" {
+		t.Errorf("uninstall flash = %q", m.flash)
+	}
+
+	// An unsaved edit adds the dirty mark without hiding the dry-run one.
+	m = press(m, "d", "y")
+	if header := m.viewHeader(); !strings.Contains(header, "[dry run]") || !strings.Contains(header, "●") {
+		t.Errorf("header = %q", header)
+	}
+
+	if m, _ = m.startAction(actInstall, nil); m.flash != "nothing to install" {
+		t.Errorf("no targets flash = %q", m.flash)
+	}
+}
+
+func TestActionDoneRunsQueueThenRefreshes(t *testing.T) {
+	m := fixtureModel(t)
+	m.brew = &fakeBrew{state: &installedState{}}
+	m.actQueue = [][]string{{"brew", "install", "--cask", "vlc"}}
+	m.actDesc = "install 2 entries"
+
+	m, cmd := m.actionDone(actionDoneMsg{argv: []string{"brew", "install", "ffmpeg"}})
+	if cmd == nil || len(m.actQueue) != 0 {
+		t.Fatalf("queue not advanced: %q", m.actQueue)
+	}
+	m, cmd = m.actionDone(actionDoneMsg{argv: []string{"brew", "install", "--cask", "vlc"}})
+	if cmd == nil || m.flash != "done: install 2 entries" {
+		t.Errorf("final: flash = %q", m.flash)
+	}
+
+	m.actFailed = []string{"brew install exited 1"}
+	m, _ = m.actionDone(actionDoneMsg{argv: []string{"brew", "install", "x"}})
+	if !strings.Contains(m.flash, "exited 1") {
+		t.Errorf("failure flash = %q", m.flash)
+	}
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	bfile "mrk-brewfile"
+)
+
+// ── Homebrew backend ──────────────────────────────────────────────────────
+
+// pkgInfo is what bf needs from `brew info --json=v2` about one formula or
+// cask.
+type pkgInfo struct {
+	Name     string
+	Kind     pkgKind // kindBrew or kindCask
+	Desc     string
+	Version  string
+	Tap      string
+	Homepage string
+}
+
+// brewBackend answers package questions for bf. The real one shells out to
+// brew; tests substitute a fake.
+type brewBackend interface {
+	// Info returns every formula and cask called name; none is not an error.
+	Info(name string) ([]pkgInfo, error)
+	// Names lists all known formula or cask names, for typo suggestions.
+	Names(kind pkgKind) ([]string, error)
+	// Search returns formulae and casks matching query, best first.
+	Search(query string) ([]pkgInfo, error)
+	// Installed reports installed, outdated and pinned formulae and casks
+	// and the tapped taps.
+	Installed() (*installedState, error)
+	// TapNames lists the formulae and casks a tapped tap provides.
+	TapNames(tap string) ([]string, error)
+	// Leaves lists formulae installed on request that nothing else
+	// installed depends on.
+	Leaves() ([]string, error)
+	// Deps maps each installed formula and cask to the formulae it depends
+	// on.
+	Deps() (map[string][]string, error)
+	// LockInfo reports the installed version, tap and revision of each
+	// installed formula and cask, by short name, and the commit each tapped
+	// tap is at.
+	LockInfo() (map[entryKey]bfile.LockEntry, error)
+	// Detail returns the full record for one formula or cask, for the
+	// detail view.
+	Detail(kind pkgKind, name string) (pkgDetail, error)
+}
+
+// execBrew runs the brew binary at bin. Name listing and search prefer the
+// formula and cask JSON API cache brew keeps for itself, which has every
+// name and description and needs no subprocess per keystroke.
+type execBrew struct {
+	bin string
+
+	mu        sync.Mutex
+	names     map[pkgKind][]string
+	catalogue []pkgInfo
+	catLoaded bool
+}
+
+func newExecBrew() *execBrew {
+	return &execBrew{bin: "brew"}
+}
+
+func (b *execBrew) Info(name string) ([]pkgInfo, error) {
+	if _, err := exec.LookPath(b.bin); err != nil {
+		return nil, fmt.Errorf("%s not found", b.bin)
+	}
+	var infos []pkgInfo
+	for _, flag := range []string{"--formula", "--cask"} {
+		// brew exits non-zero for an unknown name; that just means no match.
+		out, err := exec.Command(b.bin, "info", "--json=v2", flag, name).Output()
+		if err != nil {
+			continue
+		}
+		found, err := parseBrewInfo(out)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, found...)
+	}
+	return infos, nil
+}
+
+func (b *execBrew) Names(kind pkgKind) ([]string, error) {
+	if cat := b.catalog(); cat != nil {
+		var names []string
+		for _, p := range cat {
+			if p.Kind == kind {
+				names = append(names, p.Name)
+			}
+		}
+		return names, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if names, ok := b.names[kind]; ok {
+		return names, nil
+	}
+	sub := "formulae"
+	if kind == kindCask {
+		sub = "casks"
+	}
+	out, err := exec.Command(b.bin, sub).Output()
+	if err != nil {
+		return nil, fmt.Errorf("brew %s: %w", sub, err)
+	}
+	if b.names == nil {
+		b.names = make(map[pkgKind][]string)
+	}
+	b.names[kind] = strings.Fields(string(out))
+	return b.names[kind], nil
+}
+
+// searchLimit caps how many matches the add flow shows.
+const searchLimit = 50
+
+func (b *execBrew) Search(query string) ([]pkgInfo, error) {
+	if cat := b.catalog(); cat != nil {
+		return searchCatalogue(cat, query, searchLimit), nil
+	}
+	if _, err := exec.LookPath(b.bin); err != nil {
+		return nil, fmt.Errorf("%s not found", b.bin)
+	}
+	var out []pkgInfo
+	for _, kind := range []pkgKind{kindBrew, kindCask} {
+		flag := "--formula"
+		if kind == kindCask {
+			flag = "--cask"
+		}
+		// brew search exits non-zero when nothing matches.
+		res, _ := exec.Command(b.bin, "search", flag, query).Output()
+		for _, line := range strings.Split(string(res), "\n") {
+			name := strings.TrimSpace(line)
+			if name == "" || strings.HasPrefix(name, "==>") {
+				continue
+			}
+			out = append(out, pkgInfo{Name: name, Kind: kind})
+		}
+	}
+	return searchCatalogue(out, query, searchLimit), nil
+}
+
+// catalog loads brew's API cache once; nil means it isn't there (no brew,
+// or HOMEBREW_NO_INSTALL_FROM_API) and callers fall back to subprocesses.
+func (b *execBrew) catalog() []pkgInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.catLoaded {
+		return b.catalogue
+	}
+	b.catLoaded = true
+	dir := os.Getenv("HOMEBREW_CACHE")
+	if dir == "" {
+		out, err := exec.Command(b.bin, "--cache").Output()
+		if err != nil {
+			return nil
+		}
+		dir = strings.TrimSpace(string(out))
+	}
+	formulae := readAPICache(filepath.Join(dir, "api"), "formula", kindBrew)
+	casks := readAPICache(filepath.Join(dir, "api"), "cask", kindCask)
+	if formulae == nil && casks == nil {
+		return nil
+	}
+	b.catalogue = append(formulae, casks...)
+	return b.catalogue
+}
+
+// readAPICache reads <dir>/<base>.jws.json, the signed form newer brews
+// write, or the plain <base>.json older ones did.
+func readAPICache(dir, base string, kind pkgKind) []pkgInfo {
+	var items []struct {
+		Name  string `json:"name"`
+		Token string `json:"token"`
+		Desc  string `json:"desc"`
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, base+".jws.json")); err == nil {
+		var jws struct {
+			Payload string `json:"payload"`
+		}
+		if json.Unmarshal(data, &jws) != nil || json.Unmarshal([]byte(jws.Payload), &items) != nil {
+			return nil
+		}
+	} else if data, err := os.ReadFile(filepath.Join(dir, base+".json")); err == nil {
+		if json.Unmarshal(data, &items) != nil {
+			return nil
+		}
+	} else {
+		return nil
+	}
+	out := make([]pkgInfo, 0, len(items))
+	for _, it := range items {
+		name := it.Name
+		if kind == kindCask {
+			name = it.Token
+		}
+		out = append(out, pkgInfo{Name: name, Kind: kind, Desc: it.Desc})
+	}
+	return out
+}
+
+// searchCatalogue ranks case-insensitive matches: exact name, name prefix,
+// name substring, then description substring.
+func searchCatalogue(cat []pkgInfo, query string, limit int) []pkgInfo {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return nil
+	}
+	type ranked struct {
+		p    pkgInfo
+		rank int
+	}
+	var hits []ranked
+	for _, p := range cat {
+		name := strings.ToLower(p.Name)
+		switch {
+		case name == q:
+			hits = append(hits, ranked{p, 0})
+		case strings.HasPrefix(name, q):
+			hits = append(hits, ranked{p, 1})
+		case strings.Contains(name, q):
+			hits = append(hits, ranked{p, 2})
+		case strings.Contains(strings.ToLower(p.Desc), q):
+			hits = append(hits, ranked{p, 3})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].rank != hits[j].rank {
+			return hits[i].rank < hits[j].rank
+		}
+		if hits[i].p.Name != hits[j].p.Name {
+			return hits[i].p.Name < hits[j].p.Name
+		}
+		return hits[i].p.Kind < hits[j].p.Kind
+	})
+	out := make([]pkgInfo, 0, min(limit, len(hits)))
+	for i := 0; i < len(hits) && i < limit; i++ {
+		out = append(out, hits[i].p)
+	}
+	return out
+}
+
+// brewInfoJSON is the subset of `brew info --json=v2` that bf reads.
+type brewInfoJSON struct {
+	Formulae []struct {
+		Name     string `json:"name"`
+		Desc     string `json:"desc"`
+		Tap      string `json:"tap"`
+		Homepage string `json:"homepage"`
+		Versions struct {
+			Stable string `json:"stable"`
+		} `json:"versions"`
+		Revision  int `json:"revision"`
+		Installed []struct {
+			Version string `json:"version"`
+		} `json:"installed"`
+		Dependencies []string `json:"dependencies"`
+		Caveats      *string  `json:"caveats"`
+	} `json:"formulae"`
+	Casks []struct {
+		Token     string  `json:"token"`
+		Desc      string  `json:"desc"`
+		Tap       string  `json:"tap"`
+		Homepage  string  `json:"homepage"`
+		Version   string  `json:"version"`
+		Installed string  `json:"installed"`
+		Caveats   *string `json:"caveats"`
+		DependsOn struct {
+			Formula []string `json:"formula"`
+		} `json:"depends_on"`
+		Artifacts []map[string]json.RawMessage `json:"artifacts"`
+	} `json:"casks"`
+}
+
+func parseBrewInfo(data []byte) ([]pkgInfo, error) {
+	var raw brewInfoJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("brew info: %w", err)
+	}
+	var infos []pkgInfo
+	for _, f := range raw.Formulae {
+		infos = append(infos, pkgInfo{Name: f.Name, Kind: kindBrew, Desc: f.Desc,
+			Version: f.Versions.Stable, Tap: f.Tap, Homepage: f.Homepage})
+	}
+	for _, c := range raw.Casks {
+		infos = append(infos, pkgInfo{Name: c.Token, Kind: kindCask, Desc: c.Desc,
+			Version: c.Version, Tap: c.Tap, Homepage: c.Homepage})
+	}
+	return infos, nil
+}
+
+// ── Add-flow lookup ───────────────────────────────────────────────────────
+
+// lookupMsg is the result of validating a name typed into the add flow.
+type lookupMsg struct {
+	name        string
+	infos       []pkgInfo
+	suggestions []string
+	err         error
+}
+
+// lookupPackage checks name against Homebrew and, when nothing matches,
+// collects close formula and cask names as suggestions.
+func lookupPackage(b brewBackend, name string) tea.Cmd {
+	return func() tea.Msg {
+		infos, err := b.Info(name)
+		if err != nil || len(infos) > 0 {
+			return lookupMsg{name: name, infos: infos, err: err}
+		}
+		var known []string
+		for _, k := range []pkgKind{kindBrew, kindCask} {
+			if names, err := b.Names(k); err == nil {
+				known = append(known, names...)
+			}
+		}
+		return lookupMsg{name: name, suggestions: closeMatches(name, known, 3)}
+	}
+}
+
+// closeMatches returns up to n candidates within a small edit distance of
+// name, nearest first.
+func closeMatches(name string, candidates []string, n int) []string {
+	type scored struct {
+		name string
+		dist int
+	}
+	limit := max(1, min(3, len(name)/3))
+	seen := map[string]bool{}
+	var hits []scored
+	for _, c := range candidates {
+		if c == name || seen[c] {
+			continue
+		}
+		seen[c] = true
+		if d := editDistance(name, c); d <= limit {
+			hits = append(hits, scored{c, d})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].dist != hits[j].dist {
+			return hits[i].dist < hits[j].dist
+		}
+		return hits[i].name < hits[j].name
+	})
+	var out []string
+	for i := 0; i < len(hits) && i < n; i++ {
+		out = append(out, hits[i].name)
+	}
+	return out
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// submitAddName starts validating the typed name. A name Homebrew already
+// rejected goes straight to the manual type picker, for taps not yet tapped
+// and the non-Homebrew kinds.
+func (m model) submitAddName() (model, tea.Cmd) {
+	m.addName = strings.TrimSpace(m.inputBuf)
+	m.inputBuf = ""
+	if m.addName == m.addChecked || m.brew == nil {
+		m.addKindIdx = 0
+		if strings.Count(m.addName, "/") == 1 {
+			m.addKindIdx = slices.Index(addKinds, kindTap)
+		}
+		m.state = stateAddKind
+		return m, nil
+	}
+	m.state = stateAddLookup
+	return m, lookupPackage(m.brew, m.addName)
+}
+
+// applyLookup moves the add flow on from a Homebrew lookup: straight to the
+// section picker when the name is exactly one new formula or cask.
+func (m model) applyLookup(msg lookupMsg) model {
+	if msg.err != nil {
+		m.flash = "brew lookup failed: " + msg.err.Error()
+		m.addKindIdx = 0
+		m.state = stateAddKind
+		return m
+	}
+	if len(msg.infos) == 0 {
+		m.addChecked = msg.name
+		m.inputBuf = msg.name
+		m.state = stateAddName
+		m.flash = "not found in Homebrew"
+		if len(msg.suggestions) > 0 {
+			m.flash += " — did you mean " + strings.Join(msg.suggestions, ", ") + "?"
+		}
+		m.flash += " (enter again to add anyway)"
+		return m
+	}
+
+	// Drop kinds that are already in the Brewfile, noting where they are.
+	var fresh []pkgInfo
+	var dup *entry
+	for _, info := range msg.infos {
+		if m.descCache == nil {
+			m.descCache = make(map[string]string)
+		}
+		if info.Desc != "" {
+			m.descCache[info.Name] = info.Desc
+		}
+		if e := m.bf.doc.Find(info.Kind, info.Name); e != nil {
+			dup = e
+			continue
+		}
+		fresh = append(fresh, info)
+	}
+
+	switch {
+	case len(fresh) == 0:
+		m = m.jumpTo(dup)
+		m.flash = fmt.Sprintf("%s \"%s\" is already in %s", dup.Kind, dup.Name, m.bf.doc.SectionOf(dup).Name)
+		m.state = stateNormal
+	case len(fresh) > 1:
+		m.flash = "both a formula and a cask — pick one"
+		m.addKindIdx = 0
+		m.state = stateAddKind
+	default:
+		info := fresh[0]
+		if !strings.Contains(m.addName, "/") {
+			m.addName = info.Name // brew resolves aliases and old names
+		}
+		m.addKind = info.Kind
+		m.addOpts = nil
+		m.addSecIdx = m.secIdx
+		m.flash = fmt.Sprintf("%s %s", info.Kind, info.Name)
+		if info.Desc != "" {
+			m.flash += ": " + info.Desc
+		}
+		if dup != nil {
+			m.flash += fmt.Sprintf(" (%s already in %s)", dup.Kind, m.bf.doc.SectionOf(dup).Name)
+		}
+		m.state = stateAddSection
+	}
+	return m
+}
+
+// ── Add-flow search ───────────────────────────────────────────────────────
+
+// addSearchDelay is how long typing must pause before the add flow searches,
+// so a fast typist doesn't queue a brew search per keystroke.
+const addSearchDelay = 150 * time.Millisecond
+
+type addSearchTickMsg struct{ seq int }
+
+type addSearchMsg struct {
+	seq     int
+	results []pkgInfo
+	err     error
+}
+
+func searchBrew(b brewBackend, seq int, query string) tea.Cmd {
+	return func() tea.Msg {
+		res, err := b.Search(query)
+		return addSearchMsg{seq: seq, results: res, err: err}
+	}
+}
+
+// queueAddSearch restarts the debounce after the add-flow input changed.
+func (m model) queueAddSearch() (model, tea.Cmd) {
+	m.addSeq++
+	m.addResIdx = -1
+	if strings.TrimSpace(m.inputBuf) == "" || m.brew == nil {
+		m.addResults = nil
+		return m, nil
+	}
+	seq := m.addSeq
+	return m, tea.Tick(addSearchDelay, func(time.Time) tea.Msg { return addSearchTickMsg{seq} })
+}
+
+// applyAddSearch shows fresh results and fetches any descriptions the
+// backend didn't supply, through the same cache as the package pane.
+func (m model) applyAddSearch(msg addSearchMsg) (model, tea.Cmd) {
+	m.addResults = msg.results
+	m.addResIdx = min(m.addResIdx, len(m.addResults)-1)
+	var missing []*entry
+	for _, r := range m.addResults {
+		if _, ok := m.descCache[r.Name]; !ok && r.Desc == "" {
+			missing = append(missing, bfile.NewEntry(r.Kind, r.Name))
+		}
+	}
+	if len(missing) == 0 {
+		return m, nil
+	}
+	return m, fetchSectionDescs(missing)
+}
+
+// handleAddName drives the name field of the add flow: typing searches
+// Homebrew, ↑↓ pick a match, and enter adds the match to the current
+// section or validates the typed name when no match is highlighted.
+func (m model) handleAddName(key string, msg tea.KeyMsg) (model, tea.Cmd) {
+	switch key {
+	case "down", "ctrl+n":
+		if m.addResIdx < len(m.addResults)-1 {
+			m.addResIdx++
+		}
+		return m, nil
+	case "up", "ctrl+p":
+		if m.addResIdx >= 0 {
+			m.addResIdx--
+		}
+		return m, nil
+	case "enter":
+		if m.addResIdx >= 0 && m.addResIdx < len(m.addResults) {
+			return m.addSearchResult(m.addResults[m.addResIdx]), nil
+		}
+		if strings.TrimSpace(m.inputBuf) != "" {
+			return m.submitAddName()
+		}
+		return m, nil
+	}
+	before := m.inputBuf
+	m, cmd := m.handleInputState(key, msg, nil)
+	if m.state == stateAddName && m.inputBuf != before {
+		return m.queueAddSearch()
+	}
+	return m, cmd
+}
+
+// addSearchResult adds a picked match to the section under the cursor.
+func (m model) addSearchResult(r pkgInfo) model {
+	m.addName, m.addKind, m.addOpts = r.Name, r.Kind, nil
+	m.inputBuf = ""
+	m.addResults = nil
+	if e := m.bf.doc.Find(r.Kind, r.Name); e != nil {
+		m = m.jumpTo(e)
+		m.flash = fmt.Sprintf("%s \"%s\" is already in %s", e.Kind, e.Name, m.bf.doc.SectionOf(e).Name)
+		m.state = stateNormal
+		return m
+	}
+	sec := m.currentSection()
+	if sec == nil {
+		m.addSecIdx = 0
+		m.state = stateAddSection
+		return m
+	}
+	m.checkpoint(fmt.Sprintf("add %s \"%s\"", r.Kind, r.Name))
+	m.bf.addEntry(r.Name, r.Kind, false, sec.Name)
+	m.dirty = true
+	if e := m.bf.doc.Find(r.Kind, r.Name); e != nil {
+		m = m.jumpTo(e)
+	}
+	m.flash = fmt.Sprintf("added %s \"%s\" to %s", r.Kind, r.Name, sec.Name)
+	m.state = stateNormal
+	return m
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	bfile "mrk-brewfile"
+)
+
+// fakeBrew answers from a fixed catalogue and installed state instead of
+// running brew.
+type fakeBrew struct {
+	pkgs   map[string][]pkgInfo
+	state  *installedState
+	taps   map[string][]string
+	leaves []string
+	deps   map[string][]string
+
+	details     map[string]pkgDetail // keyed by detailKey
+	detailCalls int
+}
+
+func (f *fakeBrew) Info(name string) ([]pkgInfo, error) { return f.pkgs[name], nil }
+
+func (f *fakeBrew) Names(kind pkgKind) ([]string, error) {
+	var names []string
+	for _, infos := range f.pkgs {
+		for _, i := range infos {
+			if i.Kind == kind {
+				names = append(names, i.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func (f *fakeBrew) Search(query string) ([]pkgInfo, error) {
+	var all []pkgInfo
+	for _, infos := range f.pkgs {
+		all = append(all, infos...)
+	}
+	return searchCatalogue(all, query, searchLimit), nil
+}
+
+func (f *fakeBrew) Leaves() ([]string, error) { return f.leaves, nil }
+
+func (f *fakeBrew) Deps() (map[string][]string, error) { return f.deps, nil }
+
+// LockInfo is only used by bf lock, which tests drive through buildLock.
+func (f *fakeBrew) LockInfo() (map[entryKey]bfile.LockEntry, error) { return nil, nil }
+
+func (f *fakeBrew) Detail(kind pkgKind, name string) (pkgDetail, error) {
+	f.detailCalls++
+	d, ok := f.details[detailKey(kind, name)]
+	if !ok {
+		return pkgDetail{}, errors.New("no available formula with the name " + name)
+	}
+	d.Fetched = time.Now()
+	return d, nil
+}
+
+func (f *fakeBrew) TapNames(tap string) ([]string, error) {
+	names, ok := f.taps[tap]
+	if !ok {
+		return nil, errors.New("not tapped")
+	}
+	return names, nil
+}
+
+func (f *fakeBrew) Installed() (*installedState, error) {
+	if f.state == nil {
+		return nil, errors.New("brew not found")
+	}
+	return f.state, nil
+}
+
+var testCatalogue = &fakeBrew{pkgs: map[string][]pkgInfo{
+	"ripgrep":  {{Name: "ripgrep", Kind: kindBrew, Desc: "Search tool like grep"}},
+	"iterm2":   {{Name: "iterm2", Kind: kindCask, Desc: "Terminal emulator"}},
+	"docker":   {{Name: "docker", Kind: kindBrew}, {Name: "docker", Kind: kindCask}},
+	"ffmpeg":   {{Name: "ffmpeg", Kind: kindBrew}},
+	"firefox":  {{Name: "firefox", Kind: kindCask}},
+	"fireflyd": {{Name: "fireflyd", Kind: kindBrew}},
+}}
+
+// addName types name into the add flow and delivers the lookup result.
+func addName(t *testing.T, m model, name string) model {
+	t.Helper()
+	m = press(m, "a", name)
+	m, cmd := m.handleKey(keyMsg("enter"))
+	if m.state == stateAddLookup {
+		if cmd == nil {
+			t.Fatal("lookup state without a command")
+		}
+		next, _ := m.Update(cmd())
+		m = next.(model)
+	}
+	return m
+}
+
+func TestParseBrewInfo(t *testing.T) {
+	data := `{"formulae":[{"name":"git","desc":"Distributed revision control","tap":"homebrew/core",
+		"homepage":"https://git-scm.com","versions":{"stable":"2.47.0"}}],
+		"casks":[{"token":"vlc","desc":"Multimedia player","tap":"homebrew/cask","version":"3.0.21"}]}`
+	got, err := parseBrewInfo([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pkgInfo{
+		{Name: "git", Kind: kindBrew, Desc: "Distributed revision control", Version: "2.47.0",
+			Tap: "homebrew/core", Homepage: "https://git-scm.com"},
+		{Name: "vlc", Kind: kindCask, Desc: "Multimedia player", Version: "3.0.21", Tap: "homebrew/cask"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBrewInfo = %+v", got)
+	}
+}
+
+func TestAddLookupDetectsKind(t *testing.T) {
+	m := fixtureModel(t)
+	m.brew = testCatalogue
+
+	m = addName(t, m, "iterm2")
+	if m.state != stateAddSection || m.addKind != kindCask {
+		t.Fatalf("state=%v kind=%v flash=%q", m.state, m.addKind, m.flash)
+	}
+	m.addSecIdx = 2 // Casks
+	m = press(m, "enter")
+	if e := m.bf.doc.Find(kindCask, "iterm2"); e == nil || m.bf.doc.SectionOf(e).Name != "Casks" {
+		t.Errorf("iterm2 not added as a cask in Casks")
+	}
+
+	m = addName(t, m, "docker")
+	if m.state != stateAddKind || !strings.Contains(m.flash, "pick one") {
+		t.Errorf("ambiguous name: state=%v flash=%q", m.state, m.flash)
+	}
+}
+
+func TestAddLookupSuggestsAndFlagsDuplicates(t *testing.T) {
+	m := fixtureModel(t)
+	m.brew = testCatalogue
+
+	m = addName(t, m, "firefx")
+	if m.state != stateAddName || !strings.Contains(m.flash, "did you mean firefox?") {
+		t.Fatalf("typo: state=%v flash=%q", m.state, m.flash)
+	}
+	// Entering the same name again skips validation.
+	m, _ = m.handleKey(keyMsg("enter"))
+	if m.state != stateAddKind {
+		t.Errorf("second enter: state=%v", m.state)
+	}
+
+	m = press(m, "esc")
+	m = addName(t, m, "ffmpeg")
+	if m.state != stateNormal || m.flash != `brew "ffmpeg" is already in Media` {
+		t.Errorf("duplicate: state=%v flash=%q", m.state, m.flash)
+	}
+	if e := m.currentEntry(); e == nil || e.Name != "ffmpeg" {
+		t.Errorf("cursor not moved to the existing entry: %v", e)
+	}
+}
+
+func TestCloseMatches(t *testing.T) {
+	names := []string{"ripgrep", "ripgrep-all", "grep", "git", "gti", "firefox"}
+	if got := closeMatches("ripgrp", names, 3); !reflect.DeepEqual(got, []string{"ripgrep"}) {
+		t.Errorf("closeMatches(ripgrp) = %q", got)
+	}
+	if got := closeMatches("gt", names, 3); !reflect.DeepEqual(got, []string{"git", "gti"}) {
+		t.Errorf("closeMatches(gt) = %q", got)
+	}
+}
+
+// deliver runs cmd and feeds its message back into the model, as the
+// Bubble Tea runtime would once a tick or search completes.
+func deliver(t *testing.T, m model, cmd tea.Cmd) (model, tea.Cmd) {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("expected a command")
+	}
+	next, cmd := m.Update(cmd())
+	return next.(model), cmd
+}
+
+func TestAddSearchPicksIntoCurrentSection(t *testing.T) {
+	m := fixtureModel(t)
+	m.brew = testCatalogue
+	m = press(m, "j") // Media
+
+	m = press(m, "a", "fire")
+	m, cmd := m.handleKey(keyMsg("f"))
+	stale := m.addSeq
+	m, cmd = m.handleKey(keyMsg("l"))
+	if msg := cmd(); msg.(addSearchTickMsg).seq == stale {
+		t.Fatal("keystroke did not restart the debounce")
+	}
+	m, cmd = deliver(t, m, func() tea.Msg { return addSearchTickMsg{m.addSeq} })
+	m, _ = deliver(t, m, cmd)
+
+	var names []string
+	for _, r := range m.addResults {
+		names = append(names, r.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"fireflyd"}) {
+		t.Fatalf("results = %q", names)
+	}
+	m = press(m, "down", "enter")
+	e := m.bf.doc.Find(kindBrew, "fireflyd")
+	if e == nil || m.bf.doc.SectionOf(e).Name != "Media" || m.currentEntry() != e {
+		t.Errorf("fireflyd not added to Media under the cursor; flash=%q", m.flash)
+	}
+
+	// A stale search result is ignored.
+	m = press(m, "a", "x")
+	m.Update(addSearchMsg{seq: m.addSeq - 1, results: []pkgInfo{{Name: "old"}}})
+	if len(m.addResults) != 0 {
+		t.Errorf("stale results applied: %v", m.addResults)
+	}
+}
+
+func TestReadAPICache(t *testing.T) {
+	dir := t.TempDir()
+	payload := `[{"token":"vlc","desc":"Multimedia player"},{"token":"iina","desc":"Video player"}]`
+	jws, _ := json.Marshal(map[string]string{"payload": payload})
+	if err := os.WriteFile(filepath.Join(dir, "cask.jws.json"), jws, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "formula.json"), []byte(`[{"name":"mpv","desc":"Media player"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cat := append(readAPICache(dir, "formula", kindBrew), readAPICache(dir, "cask", kindCask)...)
+	got := searchCatalogue(cat, "player", 10)
+	want := []pkgInfo{
+		{Name: "iina", Kind: kindCask, Desc: "Video player"},
+		{Name: "mpv", Kind: kindBrew, Desc: "Media player"},
+		{Name: "vlc", Kind: kindCask, Desc: "Multimedia player"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("search = %+v", got)
+	}
+	if got := searchCatalogue(cat, "vlc", 10); len(got) != 1 || got[0].Name != "vlc" {
+		t.Errorf("exact search = %+v", got)
+	}
+}
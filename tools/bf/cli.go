@@ -0,0 +1,483 @@
+// bf subcommands — non-interactive Brewfile edits for scripts and CI.
+// Each one loads the Brewfile, applies the same brewfile methods the TUI
+// uses, and writes the result with save().
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	bfile "mrk-brewfile"
+)
+
+type cliCommand func(args []string, stdout, stderr io.Writer) error
+
+var cliCommands = map[string]cliCommand{
+	"add":    cliAdd,
+	"rm":     cliRm,
+	"mv":     cliMv,
+	"ls":     cliLs,
+	"greedy": cliGreedy,
+	"lint":   cliLint,
+	"lock":   cliLock,
+}
+
+// errUsage marks errors caused by bad arguments; runCLI exits 2 for them.
+var errUsage = errors.New("usage")
+
+func usageErr(format string, a ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{errUsage}, a...)...)
+}
+
+func isSubcommand(s string) bool {
+	_, ok := cliCommands[s]
+	return ok
+}
+
+// runCLI dispatches args[0] to its subcommand and returns the process exit
+// code: 0 on success, 1 on failure, 2 on a usage error.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	err := cliCommands[args[0]](args[1:], stdout, stderr)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "bf %s: %v\n", args[0], strings.TrimPrefix(err.Error(), errUsage.Error()+": "))
+		return 2
+	default:
+		fmt.Fprintf(stderr, "bf %s: %v\n", args[0], err)
+		return 1
+	}
+}
+
+// ── Flags ─────────────────────────────────────────────────────────────────
+
+// cliFlags holds the flags shared by every subcommand.
+type cliFlags struct {
+	fs      *flag.FlagSet
+	file    string
+	kind    string
+	kinds   map[pkgKind]*bool
+	section string
+}
+
+func newCLIFlags(name string, stderr io.Writer) *cliFlags {
+	f := &cliFlags{fs: flag.NewFlagSet("bf "+name, flag.ContinueOnError), kinds: map[pkgKind]*bool{}}
+	f.fs.SetOutput(stderr)
+	f.fs.StringVar(&f.file, "file", defaultBrewfilePath(), "path to the Brewfile")
+	f.fs.StringVar(&f.kind, "kind", "", "entry kind: brew, cask, tap, mas, vscode or whalebrew")
+	for _, k := range bfile.Kinds() {
+		f.kinds[k] = f.fs.Bool(k.String(), false, "shorthand for --kind "+k.String())
+	}
+	return f
+}
+
+func (f *cliFlags) withSection(usage string) *cliFlags {
+	f.fs.StringVar(&f.section, "section", "", usage)
+	return f
+}
+
+// parse accepts flags before, between and after positional arguments, so
+// `bf add foo --cask` and `bf add --cask foo` both work.
+func (f *cliFlags) parse(args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := f.fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usageErr("%v", err)
+		}
+		args = f.fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		if args[0] == "--" {
+			return append(pos, args[1:]...), nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// resolveKind returns the kind chosen via --kind or a shorthand flag.
+// ok is false when none was given.
+func (f *cliFlags) resolveKind() (kind pkgKind, ok bool, err error) {
+	if f.kind != "" {
+		k, valid := bfile.ParseKind(f.kind)
+		if !valid {
+			return 0, false, usageErr("unknown kind %q", f.kind)
+		}
+		kind, ok = k, true
+	}
+	for k, set := range f.kinds {
+		if !*set {
+			continue
+		}
+		if ok && k != kind {
+			return 0, false, usageErr("conflicting kinds %s and %s", kind, k)
+		}
+		kind, ok = k, true
+	}
+	return kind, ok, nil
+}
+
+// optionFlag collects repeated --opt "key: value" flags.
+type optionFlag []bfile.Option
+
+func (o *optionFlag) String() string { return fmt.Sprint([]bfile.Option(*o)) }
+
+func (o *optionFlag) Set(s string) error {
+	opt, ok := bfile.ParseOption(s)
+	if !ok {
+		return fmt.Errorf("invalid option %q — expected key: value", s)
+	}
+	*o = append(*o, opt)
+	return nil
+}
+
+// ── Lookup ────────────────────────────────────────────────────────────────
+
+// findSection matches a section by short name or full header, ignoring case.
+func (bf *brewfile) findSection(q string) *section {
+	for _, s := range bf.sections {
+		if strings.EqualFold(s.Name, q) || strings.EqualFold(s.Header, q) {
+			return s
+		}
+	}
+	return nil
+}
+
+func (bf *brewfile) sectionNames() string {
+	var names []string
+	for _, s := range bf.sections {
+		names = append(names, fmt.Sprintf("%q", s.Name))
+	}
+	return strings.Join(names, ", ")
+}
+
+// findEntry resolves name to a single entry, narrowed by kind when hasKind
+// is set. A name that exists under several kinds is an error without it.
+func (bf *brewfile) findEntry(name string, kind pkgKind, hasKind bool) (*entry, *section, error) {
+	var hits []*entry
+	var secs []*section
+	for _, s := range bf.sections {
+		for _, e := range s.Entries {
+			if e.Name == name && (!hasKind || e.Kind == kind) {
+				hits = append(hits, e)
+				secs = append(secs, s)
+			}
+		}
+	}
+	switch len(hits) {
+	case 0:
+		if hasKind {
+			return nil, nil, fmt.Errorf("%s %q is not in the Brewfile", kind, name)
+		}
+		return nil, nil, fmt.Errorf("%q is not in the Brewfile", name)
+	case 1:
+		return hits[0], secs[0], nil
+	}
+	var kinds []string
+	for _, e := range hits {
+		kinds = append(kinds, e.Kind.String())
+	}
+	return nil, nil, fmt.Errorf("%q matches %s entries; pass --kind", name, strings.Join(kinds, " and "))
+}
+
+// defaultSection picks where a new entry goes when --section is omitted:
+// the section holding the first existing entry of the same kind.
+func (bf *brewfile) defaultSection(kind pkgKind) *section {
+	for _, s := range bf.sections {
+		for _, e := range s.Entries {
+			if e.Kind == kind {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// ── Subcommands ───────────────────────────────────────────────────────────
+
+func cliAdd(args []string, stdout, stderr io.Writer) error {
+	f := newCLIFlags("add", stderr).withSection("section to add to (default: first section with the same kind)")
+	greedy := f.fs.Bool("greedy", false, "add greedy: true (casks only)")
+	id := f.fs.String("id", "", "App Store id (required for --mas)")
+	profiles := f.fs.String("profile", "", "tag the new entries for these profiles (space- or comma-separated)")
+	var opts optionFlag
+	f.fs.Var(&opts, "opt", `extra option as "key: value" (repeatable)`)
+	names, err := f.parse(args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return usageErr("bf add <name>... [--cask|--kind K] [--section S] [--greedy] [--opt 'key: value']")
+	}
+	kind, ok, err := f.resolveKind()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		kind = kindBrew
+	}
+	if *greedy && kind != kindCask {
+		return usageErr("--greedy only applies to casks")
+	}
+	if kind == kindMas {
+		if *id == "" || strings.Trim(*id, "0123456789") != "" {
+			return usageErr("--mas needs a numeric --id")
+		}
+		opts = append(optionFlag{{Key: "id", Value: *id}}, opts...)
+	}
+
+	bf, err := loadBrewfile(f.file)
+	if err != nil {
+		return err
+	}
+	var sec *section
+	if f.section != "" {
+		if sec = bf.findSection(f.section); sec == nil {
+			return fmt.Errorf("no section %q (have %s)", f.section, bf.sectionNames())
+		}
+	} else {
+		sec = bf.defaultSection(kind)
+	}
+
+	for _, name := range names {
+		if e, s, err := bf.findEntry(name, kind, true); err == nil {
+			return fmt.Errorf("%s %q is already in the Brewfile (%s)", e.Kind, name, s.Name)
+		}
+		secName := ""
+		if sec != nil {
+			secName = sec.Name
+		}
+		bf.addEntry(name, kind, *greedy, secName, opts...)
+		if p := parseProfiles(*profiles); len(p) > 0 {
+			if e := bf.doc.Find(kind, name); e != nil {
+				bf.setProfiles(e, p)
+			}
+		}
+		where := secName
+		if where == "" {
+			where = "end of file"
+		}
+		fmt.Fprintf(stdout, "added %s %q → %s\n", kind, name, where)
+	}
+	return bf.save()
+}
+
+func cliRm(args []string, stdout, stderr io.Writer) error {
+	f := newCLIFlags("rm", stderr)
+	names, err := f.parse(args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return usageErr("bf rm <name>... [--kind K]")
+	}
+	kind, hasKind, err := f.resolveKind()
+	if err != nil {
+		return err
+	}
+
+	bf, err := loadBrewfile(f.file)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		e, _, err := bf.findEntry(name, kind, hasKind)
+		if err != nil {
+			return err
+		}
+		k := e.Kind
+		bf.deleteEntry(e)
+		fmt.Fprintf(stdout, "removed %s %q\n", k, name)
+	}
+	return bf.save()
+}
+
+func cliMv(args []string, stdout, stderr io.Writer) error {
+	f := newCLIFlags("mv", stderr).withSection("destination section")
+	names, err := f.parse(args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 || f.section == "" {
+		return usageErr("bf mv <name>... --section S [--kind K]")
+	}
+	kind, hasKind, err := f.resolveKind()
+	if err != nil {
+		return err
+	}
+
+	bf, err := loadBrewfile(f.file)
+	if err != nil {
+		return err
+	}
+	dest := bf.findSection(f.section)
+	if dest == nil {
+		return fmt.Errorf("no section %q (have %s)", f.section, bf.sectionNames())
+	}
+	target := dest.Name
+	for _, name := range names {
+		e, s, err := bf.findEntry(name, kind, hasKind)
+		if err != nil {
+			return err
+		}
+		if s.Name == target {
+			fmt.Fprintf(stdout, "%s %q already in %s\n", e.Kind, name, target)
+			continue
+		}
+		k := e.Kind
+		bf.moveEntry(e, target)
+		fmt.Fprintf(stdout, "moved %s %q → %s\n", k, name, target)
+	}
+	return bf.save()
+}
+
+// lsEntry is the JSON shape of one entry in `bf ls --json`.
+type lsEntry struct {
+	Name     string            `json:"name"`
+	Kind     string            `json:"kind"`
+	Section  string            `json:"section"`
+	Greedy   bool              `json:"greedy"`
+	Args     []string          `json:"args,omitempty"`
+	Options  map[string]string `json:"options,omitempty"`
+	Comment  string            `json:"comment,omitempty"`
+	Profiles []string          `json:"profiles,omitempty"`
+	Line     int               `json:"line"` // 1-based
+}
+
+func cliLs(args []string, stdout, stderr io.Writer) error {
+	f := newCLIFlags("ls", stderr).withSection("only list this section")
+	asJSON := f.fs.Bool("json", false, "print a JSON array")
+	profile := f.fs.String("profile", bfile.ActiveProfile(), `only list this profile's entries ("all" for every entry)`)
+	if _, err := f.parse(args); err != nil {
+		return err
+	}
+	kind, hasKind, err := f.resolveKind()
+	if err != nil {
+		return err
+	}
+
+	bf, err := loadBrewfile(f.file)
+	if err != nil {
+		return err
+	}
+	secs := bf.sections
+	if f.section != "" {
+		s := bf.findSection(f.section)
+		if s == nil {
+			return fmt.Errorf("no section %q (have %s)", f.section, bf.sectionNames())
+		}
+		secs = []*section{s}
+	}
+
+	out := []lsEntry{}
+	for _, s := range secs {
+		for _, e := range s.Entries {
+			if (hasKind && e.Kind != kind) || !e.InProfile(*profile) {
+				continue
+			}
+			le := lsEntry{
+				Name:     e.Name,
+				Kind:     e.Kind.String(),
+				Section:  s.Name,
+				Greedy:   e.Greedy(),
+				Args:     e.Args,
+				Comment:  e.Comment,
+				Profiles: e.Profiles,
+				Line:     e.Line + 1,
+			}
+			if len(e.Options) > 0 {
+				le.Options = map[string]string{}
+				for _, o := range e.Options {
+					le.Options[o.Key] = o.Value
+				}
+			}
+			out = append(out, le)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(out)
+	}
+	for _, le := range out {
+		fmt.Fprintf(stdout, "%s\t%s\t%s\n", le.Section, le.Kind, le.Name)
+	}
+	return nil
+}
+
+func cliGreedy(args []string, stdout, stderr io.Writer) error {
+	f := newCLIFlags("greedy", stderr)
+	pos, err := f.parse(args)
+	if err != nil {
+		return err
+	}
+	if len(pos) < 2 || (pos[0] != "on" && pos[0] != "off") {
+		return usageErr("bf greedy on|off <cask>...")
+	}
+	if kind, ok, err := f.resolveKind(); err != nil {
+		return err
+	} else if ok && kind != kindCask {
+		return usageErr("greedy only applies to casks")
+	}
+	want := pos[0] == "on"
+
+	bf, err := loadBrewfile(f.file)
+	if err != nil {
+		return err
+	}
+	for _, name := range pos[1:] {
+		e, _, err := bf.findEntry(name, kindCask, true)
+		if err != nil {
+			return err
+		}
+		if e.Greedy() == want {
+			fmt.Fprintf(stdout, "cask %q already greedy %s\n", name, pos[0])
+			continue
+		}
+		bf.toggleGreedy(e)
+		fmt.Fprintf(stdout, "cask %q greedy %s\n", name, pos[0])
+	}
+	return bf.save()
+}
+
+func cliLint(args []string, stdout, stderr io.Writer) error {
+	f := newCLIFlags("lint", stderr)
+	fix := f.fs.Bool("fix", false, "sort every section, keeping comments with their entries, and save")
+	if _, err := f.parse(args); err != nil {
+		return err
+	}
+
+	bf, err := loadBrewfile(f.file)
+	if err != nil {
+		return err
+	}
+	if *fix {
+		if n := bf.sortAll(); n > 0 {
+			if err := bf.save(); err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "sorted %d section(s)\n", n)
+		}
+	}
+	issues := bf.lint(tapNamesFor(newExecBrew(), bf))
+	for _, is := range issues {
+		fmt.Fprintf(stdout, "%s:%d: %s: %s\n", bf.path, is.Line+1, is.Check, is.Msg)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%d issue(s)", len(issues))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const cliFixture = `## Taps
+tap "sevmorris/tap"
+
+## CLI Tools - Media
+brew "ffmpeg"
+brew "yt-dlp"
+
+## Casks - General Applications & Utilities
+cask "firefox", greedy: true
+cask "vlc"
+`
+
+func runFixture(t *testing.T, path string, args ...string) (string, int) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code := runCLI(append(args, "--file", path), &out, &errOut)
+	return out.String() + errOut.String(), code
+}
+
+func TestCLIEditsRoundTripThroughSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Brewfile")
+	if err := os.WriteFile(path, []byte(cliFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := [][]string{
+		{"add", "handbrake-app", "--cask", "--section", "casks", "--greedy"},
+		{"add", "chromaprint", "--section", "Media"},
+		{"greedy", "off", "firefox"},
+		{"mv", "vlc", "--section", "media"},
+		{"rm", "yt-dlp"},
+	}
+	for _, args := range steps {
+		if out, code := runFixture(t, path, args...); code != 0 {
+			t.Fatalf("bf %s exited %d: %s", strings.Join(args, " "), code, out)
+		}
+	}
+
+	got, _ := os.ReadFile(path)
+	want := `## Taps
+tap "sevmorris/tap"
+
+## CLI Tools - Media
+brew "chromaprint"
+brew "ffmpeg"
+cask "vlc"
+
+## Casks - General Applications & Utilities
+cask "firefox"
+cask "handbrake-app", greedy: true
+`
+	if string(got) != want {
+		t.Errorf("Brewfile after edits:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCLIErrorsAndListing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Brewfile")
+	if err := os.WriteFile(path, []byte(cliFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"add", "ffmpeg"}, 1},                 // duplicate
+		{[]string{"add", "x", "--section", "Nope"}, 1}, // unknown section
+		{[]string{"add", "x", "--greedy"}, 2},          // greedy on a formula
+		{[]string{"add", "Xcode", "--mas"}, 2},         // missing --id
+		{[]string{"rm", "nope"}, 1},                    // not present
+		{[]string{"mv", "vlc"}, 2},                     // missing --section
+		{[]string{"greedy", "maybe", "vlc"}, 2},        // bad state
+		{[]string{"greedy", "on", "vlc", "--brew"}, 2}, // greedy on a formula
+		{[]string{"ls", "--kind", "bottle"}, 2},        // unknown kind
+	}
+	for _, tc := range cases {
+		if out, code := runFixture(t, path, tc.args...); code != tc.code {
+			t.Errorf("bf %s exited %d, want %d: %s", strings.Join(tc.args, " "), code, tc.code, out)
+		}
+	}
+	if got, _ := os.ReadFile(path); string(got) != cliFixture {
+		t.Errorf("failed commands modified the Brewfile:\n%s", got)
+	}
+
+	out, code := runFixture(t, path, "ls", "--json", "--cask")
+	if code != 0 {
+		t.Fatalf("bf ls exited %d: %s", code, out)
+	}
+	var entries []lsEntry
+	if err := json.Unmarshal([]byte(out), &entries); err != nil {
+		t.Fatalf("bf ls --json output is not JSON: %v\n%s", err, out)
+	}
+	if len(entries) != 2 || entries[0].Name != "firefox" || !entries[0].Greedy ||
+		entries[0].Section != "Casks" || entries[0].Line != 9 {
+		t.Errorf("bf ls --json --cask = %+v", entries)
+	}
+}
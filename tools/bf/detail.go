@@ -0,0 +1,428 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	theme "mrk-theme"
+)
+
+// ── Package details ───────────────────────────────────────────────────────
+
+// detailTTL is how long a cached detail record is shown without asking brew
+// again. Versions move faster than the rest; r in the detail view refreshes.
+const detailTTL = 7 * 24 * time.Hour
+
+// pkgDetail is the full `brew info` record the detail view shows.
+type pkgDetail struct {
+	pkgInfo
+	Deps      []string  // formulae it depends on
+	Caveats   string    // trimmed, "" when there are none
+	Installed string    // installed version, "" when not installed
+	Size      int64     // bytes on disk when installed, 0 when unknown
+	Fetched   time.Time // when brew was asked
+}
+
+func detailKey(kind pkgKind, name string) string { return kind.String() + ":" + name }
+
+func (b *execBrew) Detail(kind pkgKind, name string) (pkgDetail, error) {
+	if _, err := exec.LookPath(b.bin); err != nil {
+		return pkgDetail{}, fmt.Errorf("%s not found", b.bin)
+	}
+	out, err := exec.Command(b.bin, "info", "--json=v2", "--"+map[pkgKind]string{kindBrew: "formula", kindCask: "cask"}[kind], name).Output()
+	if err != nil {
+		return pkgDetail{}, fmt.Errorf("brew info %s: %w", name, err)
+	}
+	d, apps, err := parseDetail(out, kind)
+	if err != nil {
+		return pkgDetail{}, err
+	}
+	if d.Installed != "" {
+		d.Size = b.installedSize(d, apps)
+	}
+	d.Fetched = time.Now()
+	return d, nil
+}
+
+// installedSize adds up a formula's keg, or a cask's apps in /Applications
+// and its Caskroom directory.
+func (b *execBrew) installedSize(d pkgDetail, apps []string) int64 {
+	where := func(flag string) string {
+		out, err := exec.Command(b.bin, flag).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if d.Kind == kindBrew {
+		if cellar := where("--cellar"); cellar != "" {
+			return dirSize(filepath.Join(cellar, d.Name, d.Installed))
+		}
+		return 0
+	}
+	var size int64
+	for _, app := range apps {
+		size += dirSize(filepath.Join("/Applications", app))
+	}
+	if room := where("--caskroom"); room != "" {
+		size += dirSize(filepath.Join(room, d.Name))
+	}
+	return size
+}
+
+// dirSize sums the sizes of the regular files under dir, not following
+// symlinks.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, de fs.DirEntry, err error) error {
+		if err == nil && de.Type().IsRegular() {
+			if fi, err := de.Info(); err == nil {
+				size += fi.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// parseDetail reads one formula or cask from `brew info --json=v2`. apps
+// lists a cask's .app bundles, for sizing.
+func parseDetail(data []byte, kind pkgKind) (d pkgDetail, apps []string, err error) {
+	var info brewInfoJSON
+	if err := json.Unmarshal(data, &info); err != nil {
+		return d, nil, fmt.Errorf("brew info: %w", err)
+	}
+	caveats := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return strings.TrimSpace(*s)
+	}
+	switch {
+	case kind == kindBrew && len(info.Formulae) > 0:
+		f := info.Formulae[0]
+		d.pkgInfo = pkgInfo{Name: f.Name, Kind: kindBrew, Desc: f.Desc,
+			Version: f.Versions.Stable, Tap: f.Tap, Homepage: f.Homepage}
+		// Installed kegs carry the revision suffix, so the version offered
+		// does too, or an up-to-date formula would read as outdated.
+		if f.Revision > 0 {
+			d.Version += "_" + strconv.Itoa(f.Revision)
+		}
+		d.Deps, d.Caveats = f.Dependencies, caveats(f.Caveats)
+		if len(f.Installed) > 0 {
+			d.Installed = f.Installed[len(f.Installed)-1].Version
+		}
+	case kind == kindCask && len(info.Casks) > 0:
+		c := info.Casks[0]
+		d.pkgInfo = pkgInfo{Name: c.Token, Kind: kindCask, Desc: c.Desc,
+			Version: c.Version, Tap: c.Tap, Homepage: c.Homepage}
+		d.Deps, d.Caveats, d.Installed = c.DependsOn.Formula, caveats(c.Caveats), c.Installed
+		for _, a := range c.Artifacts {
+			var list []any
+			if json.Unmarshal(a["app"], &list) == nil {
+				for _, v := range list {
+					if s, ok := v.(string); ok {
+						apps = append(apps, s)
+					}
+				}
+			}
+		}
+	default:
+		return d, nil, fmt.Errorf("brew info: no %s in the output", kind)
+	}
+	return d, apps, nil
+}
+
+// ── Disk cache ────────────────────────────────────────────────────────────
+
+// defaultDetailCachePath is where bf keeps detail records between runs.
+func defaultDetailCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mrk", "bf-info.json")
+}
+
+type detailCacheMsg map[string]pkgDetail
+
+// loadDetailCache reads the cache written by saveDetailCache. A missing or
+// unreadable cache is empty.
+func loadDetailCache(path string) tea.Cmd {
+	return func() tea.Msg {
+		cache := map[string]pkgDetail{}
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, &cache)
+		}
+		return detailCacheMsg(cache)
+	}
+}
+
+// saveDetailCache writes cache through a temp file so a second bf never
+// reads half of it. Failure only costs a refetch next time.
+func saveDetailCache(path string, cache map[string]pkgDetail) tea.Cmd {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return nil
+	}
+	return func() tea.Msg {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(path), ".bf-info-*.tmp")
+		if err != nil {
+			return nil
+		}
+		_, werr := tmp.Write(data)
+		if cerr := tmp.Close(); werr != nil || cerr != nil {
+			os.Remove(tmp.Name())
+			return nil
+		}
+		os.Rename(tmp.Name(), path)
+		return nil
+	}
+}
+
+// seedDetails merges the disk cache under anything fetched since startup,
+// and fills in descriptions the panes have not asked brew for yet.
+func (m model) seedDetails(cache map[string]pkgDetail) model {
+	if m.details == nil {
+		m.details = map[string]pkgDetail{}
+	}
+	if m.descCache == nil {
+		m.descCache = map[string]string{}
+	}
+	for k, d := range cache {
+		if _, ok := m.details[k]; !ok {
+			m.details[k] = d
+		}
+		if _, ok := m.descCache[d.Name]; !ok && d.Desc != "" {
+			m.descCache[d.Name] = d.Desc
+		}
+	}
+	return m
+}
+
+// ── Detail view ───────────────────────────────────────────────────────────
+
+type detailMsg struct {
+	key    string
+	detail pkgDetail
+	deps   map[string][]string // set when the dependency graph was fetched too
+	err    error
+}
+
+// fetchDetail asks brew about e, and for the installed dependency graph the
+// first time, for the reverse-dependency line.
+func fetchDetail(brew brewBackend, e *entry, needDeps bool) tea.Cmd {
+	kind, name := e.Kind, e.Name
+	return func() tea.Msg {
+		if brew == nil {
+			return detailMsg{key: detailKey(kind, name), err: fmt.Errorf("brew not available")}
+		}
+		d, err := brew.Detail(kind, name)
+		msg := detailMsg{key: detailKey(kind, name), detail: d, err: err}
+		if needDeps && err == nil {
+			msg.deps, _ = brew.Deps()
+		}
+		return msg
+	}
+}
+
+// openDetail shows the current entry's details, asking brew only when the
+// cache has nothing fresh.
+func (m model) openDetail(refresh bool) (model, tea.Cmd) {
+	e := m.currentEntry()
+	if e == nil {
+		return m, nil
+	}
+	m.state = stateDetail
+	m.detailErr = ""
+	if e.Kind != kindBrew && e.Kind != kindCask {
+		return m, nil
+	}
+	d, ok := m.details[detailKey(e.Kind, e.Name)]
+	if ok && !refresh && time.Since(d.Fetched) < detailTTL && m.depGraph != nil {
+		return m, nil
+	}
+	m.detailLoading = true
+	return m, fetchDetail(m.brew, e, m.depGraph == nil)
+}
+
+func (m model) applyDetail(msg detailMsg) (model, tea.Cmd) {
+	m.detailLoading = false
+	if msg.deps != nil {
+		m.depGraph = msg.deps
+	}
+	if msg.err != nil {
+		// An answer for an entry already stepped past is dropped.
+		if e := m.currentEntry(); e != nil && detailKey(e.Kind, e.Name) == msg.key {
+			m.detailErr = msg.err.Error()
+		}
+		return m, nil
+	}
+	if m.details == nil {
+		m.details = map[string]pkgDetail{}
+	}
+	m.details[msg.key] = msg.detail
+	if msg.detail.Desc != "" {
+		if m.descCache == nil {
+			m.descCache = map[string]string{}
+		}
+		m.descCache[msg.detail.Name] = msg.detail.Desc
+	}
+	return m, saveDetailCache(m.detailCachePath, m.details)
+}
+
+func (m model) handleDetail(key string) (model, tea.Cmd) {
+	switch key {
+	case "esc", "q", "enter", "left", "h":
+		m.state = stateNormal
+	case "up", "k":
+		if m.entIdx > 0 {
+			m.entIdx--
+			return m.openDetail(false)
+		}
+	case "down", "j":
+		if m.entIdx < len(m.entries(m.currentSection()))-1 {
+			m.entIdx++
+			return m.openDetail(false)
+		}
+	case "r":
+		return m.openDetail(true)
+	}
+	return m, nil
+}
+
+// usedBy lists the Brewfile entries that depend on name, directly or
+// through other formulae, by the installed dependency graph.
+func (m model) usedBy(name string) []string {
+	short := name[strings.LastIndex(name, "/")+1:]
+	var out []string
+	for _, e := range m.bf.doc.Entries() {
+		if e.Kind != kindBrew && e.Kind != kindCask || e.Name == name {
+			continue
+		}
+		own := e.Name[strings.LastIndex(e.Name, "/")+1:]
+		seen := map[string]bool{}
+		queue := slices.Clone(m.depGraph[own])
+		for len(queue) > 0 {
+			d := queue[0]
+			queue = queue[1:]
+			if seen[d] {
+				continue
+			}
+			seen[d] = true
+			queue = append(queue, m.depGraph[d]...)
+		}
+		if seen[short] && !slices.Contains(out, e.Name) {
+			out = append(out, e.Name)
+		}
+	}
+	return out
+}
+
+// humanSize renders a byte count the way Finder does, in powers of 1000.
+func humanSize(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+// detailRows renders the labelled rows of the detail view.
+func (m model) detailRows(e *entry, d pkgDetail, width int) []string {
+	valW := max(1, width-14)
+	var rows []string
+	row := func(label, value string) {
+		if value == "" {
+			return
+		}
+		rows = append(rows, styleDim.Render(padRight(label, 12))+"  "+styleEntNorm.Render(theme.Truncate(value, valW)))
+	}
+	list := func(xs []string) string {
+		if len(xs) == 0 {
+			return "none"
+		}
+		return strings.Join(xs, ", ")
+	}
+
+	row("description", d.Desc)
+	row("homepage", d.Homepage)
+	version := d.Version
+	switch {
+	case d.Installed == "":
+		version += " (not installed)"
+	case d.Installed != d.Version:
+		version = d.Installed + " installed, " + d.Version + " available"
+	}
+	if locked := m.bf.lockedVersion(e); locked != "" {
+		version += " · lock " + locked
+	}
+	row("version", version)
+	row("tap", d.Tap)
+	row("depends on", list(d.Deps))
+	if m.depGraph != nil {
+		row("used by", list(m.usedBy(e.Name)))
+	}
+	if d.Size > 0 {
+		row("size", humanSize(d.Size))
+	}
+	if d.Caveats != "" {
+		rows = append(rows, "", styleDim.Render("caveats"))
+		for _, l := range strings.Split(d.Caveats, "\n") {
+			rows = append(rows, "  "+styleNote.Render(theme.Truncate(l, max(1, width-2))))
+		}
+	}
+	if !d.Fetched.IsZero() {
+		rows = append(rows, "", styleDim.Render("fetched "+d.Fetched.Format("2006-01-02 15:04")+" · r refreshes"))
+	}
+	return rows
+}
+
+func (m model) viewDetail(bodyH int) string {
+	inner := m.width - 4
+	paneH := max(bodyH-2, 1)
+
+	e := m.currentEntry()
+	if e == nil {
+		return theme.StylePaneOn.Width(inner).Height(paneH).Render(styleDim.Render("no entry selected"))
+	}
+	lines := []string{styleInputPfx.Render(fmt.Sprintf(" info › %s \"%s\"", e.Kind, e.Name)), ""}
+	d, ok := m.details[detailKey(e.Kind, e.Name)]
+	switch {
+	case e.Kind != kindBrew && e.Kind != kindCask:
+		lines = append(lines, styleDim.Render("brew info only covers formulae and casks"))
+	case m.detailErr != "":
+		lines = append(lines, styleFlashWarn.Render(m.detailErr))
+	case !ok && m.detailLoading:
+		lines = append(lines, styleDim.Render("asking brew…"))
+	case ok:
+		if m.detailLoading {
+			lines[0] += styleDim.Render("  refreshing…")
+		}
+		lines = append(lines, m.detailRows(e, d, inner)...)
+	}
+	if len(lines) > paneH {
+		lines = lines[:paneH]
+	}
+	return theme.StylePaneOn.Width(inner).Height(paneH).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDetail(t *testing.T) {
+	formula := []byte(`{"formulae":[{"name":"ffmpeg","desc":"Play, record, convert media",
+		"tap":"homebrew/core","homepage":"https://ffmpeg.org/","versions":{"stable":"7.1.1"},
+		"installed":[{"version":"7.1_1"}],"dependencies":["lame","x264"],"caveats":null}],"casks":[]}`)
+	d, _, err := parseDetail(formula, kindBrew)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := pkgDetail{pkgInfo: pkgInfo{Name: "ffmpeg", Kind: kindBrew, Desc: "Play, record, convert media",
+		Version: "7.1.1", Tap: "homebrew/core", Homepage: "https://ffmpeg.org/"},
+		Deps: []string{"lame", "x264"}, Installed: "7.1_1"}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("formula = %+v", d)
+	}
+
+	cask := []byte(`{"formulae":[],"casks":[{"token":"blackhole-2ch","desc":"Virtual audio driver",
+		"tap":"homebrew/cask","version":"0.6.1","installed":"0.6.1",
+		"caveats":"  Restart to load the driver.\n","depends_on":{"formula":["sox"]},
+		"artifacts":[{"app":["BlackHole.app"]},{"uninstall":[{"pkgutil":"audio.existential"}]},
+		{"app":[{"target":"x"}]}]}]}`)
+	d, apps, err := parseDetail(cask, kindCask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Caveats != "Restart to load the driver." || !slices.Equal(d.Deps, []string{"sox"}) || d.Installed != "0.6.1" {
+		t.Errorf("cask = %+v", d)
+	}
+	if !slices.Equal(apps, []string{"BlackHole.app"}) {
+		t.Errorf("apps = %q", apps)
+	}
+
+	// A formula rebuilt at the same version is up to date when the installed
+	// keg has the revision suffix.
+	revised := []byte(`{"formulae":[{"name":"lame","versions":{"stable":"3.100"},"revision":1,
+		"installed":[{"version":"3.100_1"}]}],"casks":[]}`)
+	d, _, err = parseDetail(revised, kindBrew)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Version != "3.100_1" {
+		t.Errorf("revised version = %q", d.Version)
+	}
+	m := fixtureModelOf(t, "brew \"lame\"\n")
+	m = press(m, "l")
+	view := strings.Join(m.detailRows(m.currentEntry(), d, 80), "\n")
+	if strings.Contains(view, "available") || !strings.Contains(view, "3.100_1") {
+		t.Errorf("up-to-date formula with a revision:\n%s", view)
+	}
+
+	if _, _, err := parseDetail(formula, kindCask); err == nil {
+		t.Error("a formula-only answer parsed as a cask")
+	}
+}
+
+func TestDetailViewFetchesOnceAndCaches(t *testing.T) {
+	m := fixtureModelOf(t, "brew \"lame\"\nbrew \"ffmpeg\"\ncask \"handbrake-app\"\n")
+	m.detailCachePath = filepath.Join(t.TempDir(), "mrk", "bf-info.json")
+	fake := &fakeBrew{
+		details: map[string]pkgDetail{
+			"brew:lame": {pkgInfo: pkgInfo{Name: "lame", Kind: kindBrew, Desc: "MP3 encoder", Version: "3.100"},
+				Installed: "3.100", Size: 2_500_000},
+			"brew:ffmpeg": {pkgInfo: pkgInfo{Name: "ffmpeg", Kind: kindBrew, Version: "7.1.1"},
+				Deps: []string{"lame"}},
+		},
+		deps: map[string][]string{"ffmpeg": {"lame"}, "handbrake-app": {"ffmpeg"}},
+	}
+	m.brew = fake
+
+	m, cmd := m.handleKey(keyMsg("l"))
+	m, cmd = m.handleKey(keyMsg("enter"))
+	if m.state != stateDetail || !m.detailLoading || cmd == nil {
+		t.Fatalf("state=%v loading=%v", m.state, m.detailLoading)
+	}
+	next, save := m.Update(cmd())
+	m = next.(model)
+	if save == nil {
+		t.Fatal("detail not saved to disk")
+	}
+	save()
+
+	view := strings.Join(m.detailRows(m.currentEntry(), m.details["brew:lame"], 80), "\n")
+	for _, want := range []string{"MP3 encoder", "3.100", "ffmpeg, handbrake-app", "2.5 MB"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("detail view lacks %q:\n%s", want, view)
+		}
+	}
+	if m.descCache["lame"] != "MP3 encoder" {
+		t.Errorf("descCache = %v", m.descCache)
+	}
+
+	// Stepping back onto a cached entry does not ask brew again; r does.
+	m, cmd = m.handleDetail("j")
+	next, _ = m.Update(cmd())
+	m = next.(model)
+	m, cmd = m.handleDetail("k")
+	if cmd != nil || fake.detailCalls != 2 {
+		t.Errorf("cached entry refetched: calls=%d", fake.detailCalls)
+	}
+	if _, cmd = m.handleDetail("r"); cmd == nil {
+		t.Error("r did not refetch")
+	}
+
+	// A fresh model reads the cache from disk.
+	fresh := fixtureModelOf(t, "brew \"lame\"\n")
+	fresh = fresh.seedDetails(loadDetailCache(m.detailCachePath)().(detailCacheMsg))
+	if d := fresh.details["brew:lame"]; d.Version != "3.100" || fresh.descCache["lame"] != "MP3 encoder" {
+		t.Errorf("loaded cache = %+v", fresh.details)
+	}
+}
+
+func TestDetailCacheExpires(t *testing.T) {
+	m := fixtureModelOf(t, "brew \"lame\"\n")
+	m.brew = &fakeBrew{}
+	m.depGraph = map[string][]string{}
+	m.details = map[string]pkgDetail{
+		"brew:lame": {pkgInfo: pkgInfo{Name: "lame", Kind: kindBrew}, Fetched: time.Now().Add(-detailTTL - time.Hour)},
+	}
+	m.leftFocus = false
+	if _, cmd := m.openDetail(false); cmd == nil {
+		t.Error("stale cache entry was not refetched")
+	}
+	m.details["brew:lame"] = pkgDetail{Fetched: time.Now()}
+	if _, cmd := m.openDetail(false); cmd != nil {
+		t.Error("fresh cache entry was refetched")
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	for n, want := range map[int64]string{999: "999 B", 1500: "1.5 kB", 2_500_000: "2.5 MB", 3_000_000_000: "3.0 GB"} {
+		if got := humanSize(n); got != want {
+			t.Errorf("humanSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	bfile "mrk-brewfile"
+)
+
+// ── Diff ──────────────────────────────────────────────────────────────────
+
+// diffOp is one line of an edit script from a to b: ' ' keeps a[ai] (which
+// equals b[bi]), '-' drops a[ai], '+' inserts b[bi].
+type diffOp struct {
+	op     byte
+	ai, bi int
+}
+
+// diffLines computes a minimal line edit script with an LCS table. Brewfiles
+// are a few hundred lines, so the quadratic table is cheap.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, max(n, m))
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', i, j})
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, diffOp{'+', i, j})
+			j++
+		default:
+			ops = append(ops, diffOp{'-', i, j})
+			i++
+		}
+	}
+	return ops
+}
+
+// unifiedDiff renders a against b as a unified diff with ctx lines of
+// context. It returns nil when the two are identical.
+func unifiedDiff(aName, bName string, a, b []string, ctx int) []string {
+	ops := diffLines(a, b)
+
+	// Group changed ops into hunks, merging ones whose context overlaps.
+	type span struct{ lo, hi int } // half-open range into ops
+	var hunks []span
+	for k, op := range ops {
+		if op.op == ' ' {
+			continue
+		}
+		lo, hi := max(0, k-ctx), min(len(ops), k+ctx+1)
+		if n := len(hunks); n > 0 && lo <= hunks[n-1].hi {
+			hunks[n-1].hi = hi
+		} else {
+			hunks = append(hunks, span{lo, hi})
+		}
+	}
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	out := []string{"--- " + aName, "+++ " + bName}
+	for _, h := range hunks {
+		var aLen, bLen int
+		for _, op := range ops[h.lo:h.hi] {
+			if op.op != '+' {
+				aLen++
+			}
+			if op.op != '-' {
+				bLen++
+			}
+		}
+		first := ops[h.lo]
+		out = append(out, fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(first.ai, aLen), hunkRange(first.bi, bLen)))
+		for _, op := range ops[h.lo:h.hi] {
+			switch op.op {
+			case ' ':
+				out = append(out, " "+a[op.ai])
+			case '-':
+				out = append(out, "-"+a[op.ai])
+			case '+':
+				out = append(out, "+"+b[op.bi])
+			}
+		}
+	}
+	return out
+}
+
+// hunkRange formats a hunk's start,length the way diff -u does: 1-based,
+// and an empty range names the line before it.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+// headLines returns the Brewfile as committed at HEAD, which is the base the
+// commit flow's diff is taken against. ok is false outside a git checkout or
+// when the file is untracked.
+func (bf *brewfile) headLines() (lines []string, ok bool) {
+	gitPath, err := filepath.Rel(bf.repoRoot, bf.path)
+	if err != nil {
+		return nil, false
+	}
+	out, err := exec.Command("git", "-C", bf.repoRoot, "show", "HEAD:./"+filepath.ToSlash(gitPath)).Output()
+	if err != nil {
+		return nil, false
+	}
+	return bfile.Parse(out).Lines, true
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := strings.Split("a\nb\nc\nd\ne\nf\ng\nh\ni\nj", "\n")
+	b := strings.Split("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk", "\n")
+	got := unifiedDiff("a/x", "b/x", a, b, 1)
+	want := []string{
+		"--- a/x", "+++ b/x",
+		"@@ -1,3 +1,3 @@", " a", "-b", "+B", " c",
+		"@@ -10 +10,2 @@", " j", "+k",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unifiedDiff =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if d := unifiedDiff("a", "b", a, a, 3); d != nil {
+		t.Errorf("identical inputs gave %q", d)
+	}
+	if d := unifiedDiff("a", "b", nil, []string{"x"}, 3); !reflect.DeepEqual(d[2:], []string{"@@ -0,0 +1 @@", "+x"}) {
+		t.Errorf("insert into empty = %q", d)
+	}
+}
+
+func TestQuitAsksWhenDirty(t *testing.T) {
+	m := fixtureModel(t)
+	if _, cmd := m.handleKey(keyMsg("q")); cmd == nil {
+		t.Fatal("clean q should quit")
+	}
+
+	m = press(m, "j", "l", "d", "y", "q")
+	if m.state != stateQuitConfirm {
+		t.Fatalf("dirty q: state = %v, want quit confirm", m.state)
+	}
+	m = press(m, "n")
+	if m.state != stateNormal || !m.dirty {
+		t.Errorf("cancel: state = %v dirty = %v", m.state, m.dirty)
+	}
+
+	m = press(m, "q", "v")
+	if m.state != stateDiff || !strings.Contains(strings.Join(m.diff, "\n"), `-brew "ffmpeg"`) {
+		t.Errorf("diff from quit prompt: %q", m.diff)
+	}
+	m.width, m.height = 80, 12
+	if v := m.View(); !strings.Contains(v, "diff › unsaved changes") {
+		t.Errorf("diff view did not render:\n%s", v)
+	}
+
+	m = press(m, "q")
+	if _, cmd := m.handleKey(keyMsg("q")); cmd != nil {
+		t.Error("dirty q should not quit immediately")
+	}
+	m = press(m, "q")
+	if _, cmd := m.handleKey(keyMsg("y")); cmd == nil {
+		t.Error("y at the prompt should quit")
+	}
+}
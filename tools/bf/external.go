@@ -0,0 +1,315 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	bfile "mrk-brewfile"
+)
+
+// ── External changes ──────────────────────────────────────────────────────
+
+// errChangedOnDisk is returned by save when the Brewfile was rewritten by
+// something else (sync, git pull, another bf) since bf last read or wrote it.
+var errChangedOnDisk = errors.New("Brewfile changed on disk since it was loaded")
+
+// diskStamp identifies the file contents bf last saw. mtime and size are a
+// cheap first check; the hash decides when they differ.
+type diskStamp struct {
+	mod  time.Time
+	size int64
+	sum  [sha256.Size]byte
+}
+
+func stampOf(fi os.FileInfo, data []byte) diskStamp {
+	return diskStamp{mod: fi.ModTime(), size: fi.Size(), sum: sha256.Sum256(data)}
+}
+
+// readDisk loads the Brewfile as it is now, with its stamp.
+func (bf *brewfile) readDisk() (*bfile.File, diskStamp, error) {
+	data, err := os.ReadFile(bf.path)
+	if err != nil {
+		return nil, diskStamp{}, err
+	}
+	fi, err := os.Stat(bf.path)
+	if err != nil {
+		return nil, diskStamp{}, err
+	}
+	return bfile.Parse(data), stampOf(fi, data), nil
+}
+
+// changedOnDisk reports whether the file no longer matches the stamp taken
+// at load or the last save. A missing file is not a change: save recreates it.
+func (bf *brewfile) changedOnDisk() (bool, error) {
+	fi, err := os.Stat(bf.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if fi.ModTime().Equal(bf.stamp.mod) && fi.Size() == bf.stamp.size {
+		return false, nil
+	}
+	data, err := os.ReadFile(bf.path)
+	if err != nil {
+		return false, err
+	}
+	if sha256.Sum256(data) == bf.stamp.sum {
+		// Touched but not edited; remember the new mtime.
+		bf.stamp.mod = fi.ModTime()
+		return false, nil
+	}
+	return true, nil
+}
+
+// adopt makes the on-disk file both the saved base and the working copy.
+func (bf *brewfile) adopt(doc *bfile.File, st diskStamp) {
+	bf.doc = doc
+	bf.lines = doc.Lines
+	bf.saved = slices.Clone(doc.Lines)
+	bf.stamp = st
+	bf.reload()
+}
+
+// rebase keeps the working copy but treats theirs as the new on-disk base,
+// after the working copy has been merged onto it.
+func (bf *brewfile) rebase(doc *bfile.File, st diskStamp, merged []string) {
+	bf.doc = doc
+	bf.saved = slices.Clone(doc.Lines)
+	bf.stamp = st
+	bf.lines = merged
+	bf.reload()
+}
+
+// ── Three-way merge ───────────────────────────────────────────────────────
+
+// hunk replaces base[lo:hi] with lines. lo == hi is a pure insertion.
+type hunk struct {
+	lo, hi int
+	lines  []string
+}
+
+// hunks groups an edit script into contiguous replacements of base.
+func hunks(base, other []string) []hunk {
+	var out []hunk
+	var cur *hunk
+	for _, op := range diffLines(base, other) {
+		if op.op == ' ' {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			out = append(out, hunk{lo: op.ai, hi: op.ai})
+			cur = &out[len(out)-1]
+		}
+		if op.op == '-' {
+			cur.hi = op.ai + 1
+		} else {
+			cur.lines = append(cur.lines, other[op.bi])
+		}
+	}
+	return out
+}
+
+// overlaps reports whether two hunks touch the same base lines. Insertions
+// at the same point do not overlap; both are kept, mine first.
+func (h hunk) overlaps(o hunk) bool {
+	if h.lo == h.hi || o.lo == o.hi {
+		p, r := h, o
+		if o.lo == o.hi {
+			p, r = o, h
+		}
+		return r.lo < p.lo && p.lo < r.hi
+	}
+	return h.lo < o.hi && o.lo < h.hi
+}
+
+// before orders hunks by base position, with an insertion ahead of a
+// replacement that starts at the same line.
+func (h hunk) before(o hunk) bool {
+	return h.lo < o.lo || (h.lo == o.lo && h.lo == h.hi)
+}
+
+// mergeLines applies the edits that mine and theirs each made to base. Edits
+// to different lines combine; identical edits are taken once. conflicts
+// counts overlapping edits that differ; merged is nil when it is non-zero.
+func mergeLines(base, mine, theirs []string) (merged []string, conflicts int) {
+	a, b := hunks(base, mine), hunks(base, theirs)
+
+	var all []hunk
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b):
+			all = append(all, a[i])
+			i++
+		case i == len(a):
+			all = append(all, b[j])
+			j++
+		case a[i].lo == b[j].lo && a[i].hi == b[j].hi && slices.Equal(a[i].lines, b[j].lines):
+			all = append(all, a[i])
+			i++
+			j++
+		case a[i].overlaps(b[j]):
+			if a[i].lo != b[j].lo || a[i].hi != b[j].hi || !slices.Equal(a[i].lines, b[j].lines) {
+				conflicts++
+			}
+			all = append(all, a[i])
+			i++
+			j++
+		case a[i].before(b[j]):
+			all = append(all, a[i])
+			i++
+		default:
+			all = append(all, b[j])
+			j++
+		}
+	}
+	if conflicts > 0 {
+		return nil, conflicts
+	}
+
+	merged = make([]string, 0, len(base))
+	pos := 0
+	for _, h := range all {
+		merged = append(merged, base[pos:h.lo]...)
+		merged = append(merged, h.lines...)
+		pos = max(pos, h.hi)
+	}
+	return append(merged, base[pos:]...), 0
+}
+
+// ── Watcher ───────────────────────────────────────────────────────────────
+
+// watchInterval is how often bf --watch polls the Brewfile's mtime.
+const watchInterval = 2 * time.Second
+
+type watchMsg struct{}
+
+func watchTick() tea.Cmd {
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg { return watchMsg{} })
+}
+
+// checkExternal handles a watcher tick: a clean session follows the file,
+// a dirty one is warned so the next save can resolve the difference.
+func (m model) checkExternal() model {
+	if m.external {
+		return m
+	}
+	changed, err := m.bf.changedOnDisk()
+	if err != nil || !changed {
+		return m
+	}
+	if m.dirty {
+		m.external = true
+		m.flash = "Brewfile changed on disk — w to reload, merge or overwrite"
+		return m
+	}
+	doc, st, err := m.bf.readDisk()
+	if err != nil {
+		return m
+	}
+	m.checkpoint("reload from disk")
+	m.bf.adopt(doc, st)
+	m.clampCursor()
+	m.flash = "reloaded: Brewfile changed on disk"
+	return m
+}
+
+// ── Save flow ─────────────────────────────────────────────────────────────
+
+// afterSave is what a save was for, so a conflict prompt can carry on once
+// the user has resolved it.
+type afterSave int
+
+const (
+	afterSaveNone afterSave = iota
+	afterSaveQuit
+	afterSaveCommit
+)
+
+// trySave writes the Brewfile, stopping at the conflict prompt when the file
+// changed underneath bf.
+func (m model) trySave(then afterSave) (model, tea.Cmd) {
+	err := m.bf.save()
+	if errors.Is(err, errChangedOnDisk) {
+		m.saveThen = then
+		m.state = stateConflict
+		return m, nil
+	}
+	if err != nil {
+		m.flash = "save failed: " + err.Error()
+		m.state = stateNormal
+		return m, nil
+	}
+	return m.saved(then)
+}
+
+// saved carries on after a successful write.
+func (m model) saved(then afterSave) (model, tea.Cmd) {
+	m.dirty = false
+	m.external = false
+	switch then {
+	case afterSaveQuit:
+		return m, tea.Quit
+	case afterSaveCommit:
+		m.inputBuf = "Brewfile: "
+		m.state = stateCommit
+	default:
+		m.flash = "saved"
+		m.state = stateNormal
+	}
+	return m, nil
+}
+
+func (m model) handleConflict(key string) (model, tea.Cmd) {
+	switch key {
+	case "r":
+		doc, st, err := m.bf.readDisk()
+		if err != nil {
+			m.flash = "reload failed: " + err.Error()
+			return m, nil
+		}
+		m.checkpoint("reload from disk")
+		m.bf.adopt(doc, st)
+		m.clampCursor()
+		m.dirty = false
+		m.external = false
+		m.state = stateNormal
+		// Nothing was saved, so a pending quit or commit does not go ahead:
+		// the user may want the discarded edits back first.
+		m.flash = "reloaded from disk — your edits are one undo away"
+	case "m":
+		doc, st, err := m.bf.readDisk()
+		if err != nil {
+			m.flash = "merge failed: " + err.Error()
+			return m, nil
+		}
+		merged, conflicts := mergeLines(m.bf.saved, m.bf.lines, doc.Lines)
+		if conflicts > 0 {
+			m.flash = fmt.Sprintf("merge failed: %d overlapping edit(s) — reload or overwrite", conflicts)
+			return m, nil
+		}
+		m.checkpoint("merge with disk")
+		m.bf.rebase(doc, st, merged)
+		m.clampCursor()
+		return m.trySave(m.saveThen)
+	case "o":
+		if err := m.bf.write(); err != nil {
+			m.flash = "save failed: " + err.Error()
+			m.state = stateNormal
+			return m, nil
+		}
+		return m.saved(m.saveThen)
+	case "esc", "n", "q":
+		m.state = stateNormal
+		m.flash = "cancelled"
+	}
+	return m, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeLines(t *testing.T) {
+	base := []string{"## A", `brew "a"`, `brew "c"`, "", "## B", `cask "x"`, `cask "z"`}
+	cases := []struct {
+		name          string
+		mine, theirs  []string
+		want          []string
+		wantConflicts int
+	}{
+		{
+			name:   "edits in different sections",
+			mine:   []string{"## A", `brew "a"`, `brew "b"`, `brew "c"`, "", "## B", `cask "x"`, `cask "z"`},
+			theirs: []string{"## A", `brew "a"`, `brew "c"`, "", "## B", `cask "x", greedy: true`, `cask "z"`},
+			want:   []string{"## A", `brew "a"`, `brew "b"`, `brew "c"`, "", "## B", `cask "x", greedy: true`, `cask "z"`},
+		},
+		{
+			name:   "same edit on both sides",
+			mine:   []string{"## A", `brew "a"`, "", "## B", `cask "x"`, `cask "z"`},
+			theirs: []string{"## A", `brew "a"`, "", "## B", `cask "x"`, `cask "z"`},
+			want:   []string{"## A", `brew "a"`, "", "## B", `cask "x"`, `cask "z"`},
+		},
+		{
+			name:   "inserts at the same point keep both",
+			mine:   []string{"## A", `brew "a"`, `brew "b"`, `brew "c"`, "", "## B", `cask "x"`, `cask "z"`},
+			theirs: []string{"## A", `brew "a"`, `brew "bb"`, `brew "c"`, "", "## B", `cask "x"`, `cask "z"`},
+			want:   []string{"## A", `brew "a"`, `brew "b"`, `brew "bb"`, `brew "c"`, "", "## B", `cask "x"`, `cask "z"`},
+		},
+		{
+			name:          "different edits to one line",
+			mine:          []string{"## A", `brew "a"`, `brew "c"`, "", "## B", `cask "x", greedy: true`, `cask "z"`},
+			theirs:        []string{"## A", `brew "a"`, `brew "c"`, "", "## B", `cask "z"`},
+			wantConflicts: 1,
+		},
+	}
+	for _, tc := range cases {
+		got, conflicts := mergeLines(base, tc.mine, tc.theirs)
+		if conflicts != tc.wantConflicts || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: merged = %q (%d conflicts), want %q (%d)",
+				tc.name, got, conflicts, tc.want, tc.wantConflicts)
+		}
+	}
+}
+
+func TestSaveAfterExternalChangeMerges(t *testing.T) {
+	m := fixtureModel(t)
+	m = press(m, "j", "l", "d", "y") // remove ffmpeg
+
+	// Something else adds a cask while bf is open.
+	external := strings.Replace(cliFixture, `cask "vlc"`, `cask "vlc"`+"\ncask \"zed\"", 1)
+	if err := os.WriteFile(m.bf.path, []byte(external), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.bf.save(); !errors.Is(err, errChangedOnDisk) {
+		t.Fatalf("save() = %v, want errChangedOnDisk", err)
+	}
+
+	m = press(m, "w")
+	if m.state != stateConflict {
+		t.Fatalf("w after external change: state = %v", m.state)
+	}
+	m = press(m, "m")
+	if m.state != stateNormal || m.dirty || m.flash != "saved" {
+		t.Fatalf("merge: state=%v dirty=%v flash=%q", m.state, m.dirty, m.flash)
+	}
+	data, _ := os.ReadFile(m.bf.path)
+	if got := string(data); strings.Contains(got, `brew "ffmpeg"`) || !strings.Contains(got, `cask "zed"`) {
+		t.Errorf("merged file:\n%s", got)
+	}
+
+	// With no further external change, saving goes straight through.
+	m = press(m, "j", "u", "w")
+	if m.state != stateNormal || m.flash != "saved" {
+		t.Errorf("second save: state=%v flash=%q", m.state, m.flash)
+	}
+}
+
+func TestReloadDuringSaveAndQuitStays(t *testing.T) {
+	m := fixtureModel(t)
+	m = press(m, "j", "l", "d", "y")
+	if err := os.WriteFile(m.bf.path, []byte(cliFixture+"cask \"zed\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m = press(m, "q", "w")
+	if m.state != stateConflict {
+		t.Fatalf("save and quit after external change: state = %v", m.state)
+	}
+	m, cmd := m.handleKey(keyMsg("r"))
+	if cmd != nil || m.state != stateNormal {
+		t.Fatalf("reload quit or left the prompt: state=%v cmd=%v", m.state, cmd != nil)
+	}
+	if m.bf.doc.Find(kindCask, "zed") == nil || m.dirty {
+		t.Errorf("reload did not adopt the disk file: dirty=%v", m.dirty)
+	}
+	if m = press(m, "u"); m.bf.doc.Find(kindBrew, "ffmpeg") != nil {
+		t.Errorf("undo did not bring the edits back: %q", m.flash)
+	}
+}
+
+func TestWatcherFollowsCleanSession(t *testing.T) {
+	m := fixtureModel(t)
+	if err := os.WriteFile(m.bf.path, []byte(cliFixture+"cask \"zed\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m = m.checkExternal()
+	if m.bf.doc.Find(kindCask, "zed") == nil || m.dirty {
+		t.Errorf("clean session did not reload: dirty=%v flash=%q", m.dirty, m.flash)
+	}
+
+	m = press(m, "j", "l", "d", "y")
+	if err := os.WriteFile(m.bf.path, []byte(cliFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m = m.checkExternal()
+	if !m.external || m.bf.doc.Find(kindBrew, "ffmpeg") != nil {
+		t.Errorf("dirty session should keep edits and warn: external=%v", m.external)
+	}
+}
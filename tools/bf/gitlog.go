@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	bfile "mrk-brewfile"
+	theme "mrk-theme"
+)
+
+// ── Git history ───────────────────────────────────────────────────────────
+
+// maxLogCommits bounds how far back the history view reads.
+const maxLogCommits = 200
+
+// brewCommit is one commit that touched the Brewfile, with the packages it
+// added, removed or changed.
+type brewCommit struct {
+	hash    string
+	parent  string // first parent, "" for a root commit
+	short   string
+	date    string
+	author  string
+	subject string
+	path    string // the Brewfile's path in this commit, from the top level
+	changes []pkgChange
+}
+
+// pkgChange is one entry a commit added ('+'), removed ('-') or changed
+// ('~'), found by parsing both revisions rather than diffing lines.
+type pkgChange struct {
+	op     byte
+	kind   pkgKind
+	name   string
+	sec    string   // section it was in (removed) or went into
+	block  []string // the entry with its annotation: as removed, or as added
+	before string   // the entry's line before a change
+	after  string   // the entry's line after a change
+}
+
+// counts sums a commit's changes by kind of change.
+func (c brewCommit) counts() (added, removed, changed int) {
+	for _, ch := range c.changes {
+		switch ch.op {
+		case '+':
+			added++
+		case '-':
+			removed++
+		default:
+			changed++
+		}
+	}
+	return added, removed, changed
+}
+
+// brewChanges lists what turned old into new: changed and added entries in
+// new's order, then removed ones in old's. Entries are matched by kind and
+// name; the first occurrence of a duplicate wins.
+func brewChanges(old, new *bfile.File) []pkgChange {
+	index := func(f *bfile.File) (map[entryKey]*entry, map[*entry]string) {
+		byKey, secOf := map[entryKey]*entry{}, map[*entry]string{}
+		for _, s := range f.Sections {
+			for _, e := range s.Entries {
+				if _, dup := byKey[keyOf(e)]; !dup {
+					byKey[keyOf(e)] = e
+					secOf[e] = s.Name
+				}
+			}
+		}
+		return byKey, secOf
+	}
+	oldBy, oldSec := index(old)
+	newBy, newSec := index(new)
+
+	var out []pkgChange
+	for _, e := range new.Entries() {
+		if newBy[keyOf(e)] != e {
+			continue
+		}
+		line := strings.TrimSpace(new.Lines[e.Line])
+		o, ok := oldBy[keyOf(e)]
+		switch {
+		case !ok:
+			out = append(out, pkgChange{op: '+', kind: e.Kind, name: e.Name, sec: newSec[e],
+				block: new.Lines[e.DocLine : e.Line+1]})
+		case strings.TrimSpace(old.Lines[o.Line]) != line:
+			out = append(out, pkgChange{op: '~', kind: e.Kind, name: e.Name, sec: newSec[e],
+				before: old.Lines[o.Line], after: new.Lines[e.Line]})
+		}
+	}
+	for _, e := range old.Entries() {
+		if oldBy[keyOf(e)] != e {
+			continue
+		}
+		if _, ok := newBy[keyOf(e)]; !ok {
+			out = append(out, pkgChange{op: '-', kind: e.Kind, name: e.Name, sec: oldSec[e],
+				block: old.Lines[e.DocLine : e.Line+1]})
+		}
+	}
+	return out
+}
+
+// parseGitLog reads `git log --name-only` output in gitLogFormat. Each
+// record is the header line followed by the file's path in that commit.
+func parseGitLog(out string) []brewCommit {
+	var commits []brewCommit
+	for _, rec := range strings.Split(out, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(rec), "\n")
+		f := strings.Split(lines[0], "\x1f")
+		if len(f) != 6 {
+			continue
+		}
+		c := brewCommit{hash: f[0], short: f[1], date: f[2], author: f[3], subject: f[5]}
+		c.parent, _, _ = strings.Cut(f[4], " ")
+		for _, l := range lines[1:] {
+			if l = strings.TrimSpace(l); l != "" {
+				c.path = l
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+const gitLogFormat = "--format=%x1e%H%x1f%h%x1f%ad%x1f%an%x1f%P%x1f%s"
+
+type gitLogMsg struct {
+	commits []brewCommit
+	err     error
+}
+
+// fetchGitLog lists the commits that touched the Brewfile, following
+// renames, and parses each revision once to find what every commit changed.
+func fetchGitLog(bf *brewfile) tea.Cmd {
+	repoRoot, path := bf.repoRoot, bf.path
+	return func() tea.Msg {
+		rel, err := filepath.Rel(repoRoot, path)
+		if err != nil {
+			rel = filepath.Base(path)
+		}
+		out, err := exec.Command("git", "-C", repoRoot, "log", "--follow", "--name-only",
+			"--date=short", gitLogFormat, fmt.Sprintf("-n%d", maxLogCommits), "--", rel).Output()
+		if err != nil {
+			return gitLogMsg{err: fmt.Errorf("git log: %w", err)}
+		}
+		commits := parseGitLog(string(out))
+
+		// rev is the Brewfile at a revision, parsed once; ok is false when
+		// the file is not there.
+		revs := map[string]*bfile.File{}
+		rev := func(commit, path string) (f *bfile.File, ok bool) {
+			spec := commit + ":" + path
+			if f, ok := revs[spec]; ok {
+				return f, f != nil
+			}
+			data, err := exec.Command("git", "-C", repoRoot, "show", spec).Output()
+			if err == nil {
+				f = bfile.Parse(data)
+			}
+			revs[spec] = f
+			return f, f != nil
+		}
+		// Each commit is compared with its first parent. With merges the
+		// next listed commit may be on another branch, so it only supplies
+		// the older name when this commit renamed the file.
+		for i := range commits {
+			c := &commits[i]
+			cur, _ := rev(c.hash, c.path)
+			if cur == nil {
+				cur = bfile.Parse(nil)
+			}
+			parent := bfile.Parse(nil)
+			if c.parent != "" {
+				if f, ok := rev(c.parent, c.path); ok {
+					parent = f
+				} else if i+1 < len(commits) {
+					if f, ok := rev(c.parent, commits[i+1].path); ok {
+						parent = f
+					}
+				}
+			}
+			c.changes = brewChanges(parent, cur)
+		}
+		return gitLogMsg{commits: commits}
+	}
+}
+
+// ── Reverting one change ──────────────────────────────────────────────────
+
+// revertChange undoes ch in the working Brewfile: an added entry is deleted,
+// a removed one comes back with its comments into the same section when it
+// still exists, and a changed line gets its old text back.
+func (m model) revertChange(ch pkgChange, c brewCommit) model {
+	cur := m.bf.doc.Find(ch.kind, ch.name)
+	what := fmt.Sprintf("%s \"%s\" from %s", ch.kind, ch.name, c.short)
+	switch ch.op {
+	case '+':
+		if cur == nil {
+			m.flash = fmt.Sprintf("\"%s\" is already gone from the Brewfile", ch.name)
+			return m
+		}
+		m.checkpoint("revert adding " + what)
+		m.bf.deleteEntry(cur)
+		m.flash = "removed " + what
+	case '-':
+		if cur != nil {
+			m.flash = fmt.Sprintf("\"%s\" is already in the Brewfile (%s)", ch.name, m.bf.sectionOf(cur).Name)
+			return m
+		}
+		sec := ch.sec
+		if m.bf.findSection(sec) == nil {
+			sec = ""
+			if s := m.bf.defaultSection(ch.kind); s != nil {
+				sec = s.Name
+			}
+		}
+		m.checkpoint("revert removing " + what)
+		m.bf.insertLines(append([]string(nil), ch.block...), ch.name, ch.kind, sec)
+		m.flash = "restored " + what
+	default:
+		if cur == nil {
+			m.flash = fmt.Sprintf("\"%s\" is no longer in the Brewfile", ch.name)
+			return m
+		}
+		if strings.TrimSpace(m.bf.lines[cur.Line]) == strings.TrimSpace(ch.before) {
+			m.flash = fmt.Sprintf("\"%s\" already matches the old line", ch.name)
+			return m
+		}
+		m.checkpoint("revert change to " + what)
+		m.bf.splice(cur.Line, cur.Line+1, []string{ch.before})
+		m.flash = "reverted " + what
+	}
+	m.dirty = true
+	m.clampCursor()
+	return m
+}
+
+// ── History view ──────────────────────────────────────────────────────────
+
+func (m model) handleGitLog(key string) (model, tea.Cmd) {
+	m.flash = ""
+	switch key {
+	case "esc", "q":
+		m.state = stateNormal
+	case "up", "k":
+		if m.logIdx > 0 {
+			m.logIdx--
+		}
+	case "down", "j":
+		if m.logIdx < len(m.commits)-1 {
+			m.logIdx++
+		}
+	case "enter", "l", "right":
+		if m.logIdx < len(m.commits) {
+			if len(m.commits[m.logIdx].changes) == 0 {
+				m.flash = "no package changes in this commit"
+				break
+			}
+			m.changeIdx = 0
+			m.state = stateGitChanges
+		}
+	}
+	return m, nil
+}
+
+func (m model) handleGitChanges(key string) (model, tea.Cmd) {
+	m.flash = ""
+	c := m.commits[m.logIdx]
+	switch key {
+	case "esc", "q", "h", "left":
+		m.state = stateGitLog
+	case "up", "k":
+		if m.changeIdx > 0 {
+			m.changeIdx--
+		}
+	case "down", "j":
+		if m.changeIdx < len(c.changes)-1 {
+			m.changeIdx++
+		}
+	case "r":
+		m = m.revertChange(c.changes[m.changeIdx], c)
+	case "u":
+		m = m.undo()
+	case "enter":
+		ch := c.changes[m.changeIdx]
+		if e := m.bf.doc.Find(ch.kind, ch.name); e != nil {
+			m = m.jumpTo(e)
+			m.state = stateNormal
+		} else {
+			m.flash = fmt.Sprintf("\"%s\" is not in the Brewfile", ch.name)
+		}
+	}
+	return m, nil
+}
+
+// changeLine renders one package change for the commit view.
+func changeLine(ch pkgChange, width int) string {
+	label := fmt.Sprintf("%c %s %s", ch.op, padRight(ch.kind.String(), 5), ch.name)
+	style := styleDiffAdd
+	switch ch.op {
+	case '-':
+		style = styleMissing
+	case '~':
+		style = styleOutdated
+	}
+	detail := "(" + ch.sec + ")"
+	if ch.op == '~' {
+		detail = strings.TrimSpace(ch.before) + " → " + strings.TrimSpace(ch.after)
+	}
+	return style.Render(label) + "  " + styleDim.Render(theme.Truncate(detail, max(1, width-len([]rune(label))-2)))
+}
+
+func (m model) viewGitLog(bodyH int) string {
+	inner := m.width - 4
+	paneH := max(bodyH-2, 1)
+
+	if m.logLoading {
+		return theme.StylePaneOn.Width(inner).Height(paneH).Render(styleDim.Render("reading git history…"))
+	}
+	if len(m.commits) == 0 {
+		return theme.StylePaneOn.Width(inner).Height(paneH).Render(styleDim.Render("no commits touch the Brewfile"))
+	}
+
+	var sb strings.Builder
+	listH := max(paneH-2, 1)
+	if m.state == stateGitChanges {
+		c := m.commits[m.logIdx]
+		sb.WriteString(styleInputPfx.Render(fmt.Sprintf(" history › %s %s", c.short, theme.Truncate(c.subject, max(1, inner-20)))) + "\n")
+		sb.WriteString(styleDim.Render(fmt.Sprintf("   %s · %s", c.date, c.author)) + "\n")
+		start := max(0, m.changeIdx-listH+1)
+		for i, ch := range c.changes[start:min(len(c.changes), start+listH)] {
+			i += start
+			cursor := "  "
+			if i == m.changeIdx {
+				cursor = styleEntCursor.Render("▸ ")
+			}
+			sb.WriteString(cursor + changeLine(ch, inner-2) + "\n")
+		}
+		return theme.StylePaneOn.Width(inner).Height(paneH).Render(strings.TrimRight(sb.String(), "\n"))
+	}
+
+	sb.WriteString(styleInputPfx.Render(fmt.Sprintf(" history › %d commit(s)", len(m.commits))) + "\n\n")
+	start := max(0, m.logIdx-listH+1)
+	for i, c := range m.commits[start:min(len(m.commits), start+listH)] {
+		i += start
+		added, removed, changed := c.counts()
+		stat := styleDiffAdd.Render(padRight(fmt.Sprintf("+%d", added), 4)) +
+			styleMissing.Render(padRight(fmt.Sprintf("-%d", removed), 4)) +
+			styleOutdated.Render(padRight(fmt.Sprintf("~%d", changed), 4))
+		head := c.short + "  " + c.date + "  "
+		subject := theme.Truncate(c.subject, max(1, inner-len([]rune(head))-16))
+		if i == m.logIdx {
+			sb.WriteString(styleEntCursor.Render("▸ "+head) + stat + " " + styleEntCursor.Render(subject) + "\n")
+		} else {
+			sb.WriteString("  " + styleDim.Render(head) + stat + " " + styleEntNorm.Render(subject) + "\n")
+		}
+	}
+	return theme.StylePaneOn.Width(inner).Height(paneH).Render(strings.TrimRight(sb.String(), "\n"))
+}
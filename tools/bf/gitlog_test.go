@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	bfile "mrk-brewfile"
+)
+
+func TestParseGitLog(t *testing.T) {
+	out := "\x1eaaaa111\x1faaaa1\x1f2026-10-01\x1fSean\x1fbbbb222 cccc333\x1fAdd reaper\n\nBrewfile\n" +
+		"\x1ebbbb222\x1fbbbb2\x1f2026-09-01\x1fSean\x1f\x1fMove the Brewfile\n\nold/Brewfile\n"
+	got := parseGitLog(out)
+	if len(got) != 2 {
+		t.Fatalf("parsed %d commits: %+v", len(got), got)
+	}
+	if c := got[0]; c.hash != "aaaa111" || c.short != "aaaa1" || c.subject != "Add reaper" || c.path != "Brewfile" || c.parent != "bbbb222" {
+		t.Errorf("first commit = %+v", c)
+	}
+	if got[1].path != "old/Brewfile" {
+		t.Errorf("renamed path = %q", got[1].path)
+	}
+	if got[1].parent != "" {
+		t.Errorf("root commit parent = %q", got[1].parent)
+	}
+}
+
+// A merge's side branch is listed between a main-line commit and its parent;
+// the main-line commit must still be compared with its own parent.
+func TestFetchGitLogDiffsAgainstFirstParent(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com",
+			"-c", "commit.gpgsign=false", "-c", "init.defaultBranch=main"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	commit := func(subject string, names ...string) {
+		t.Helper()
+		var text string
+		for _, n := range names {
+			text += "brew \"" + n + "\"\n"
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Brewfile"), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "Brewfile")
+		git("commit", "-q", "-m", subject)
+	}
+
+	git("init", "-q")
+	commit("init", "a", "k", "m", "z")
+	git("checkout", "-q", "-b", "side")
+	commit("side", "a", "b", "k", "m", "z")
+	git("checkout", "-q", "main")
+	commit("main", "a", "k", "m", "y", "z")
+	git("merge", "-q", "--no-edit", "side")
+
+	msg := fetchGitLog(&brewfile{path: filepath.Join(dir, "Brewfile"), repoRoot: dir})().(gitLogMsg)
+	if msg.err != nil {
+		t.Fatal(msg.err)
+	}
+	got := map[string][]string{}
+	for _, c := range msg.commits {
+		for _, ch := range c.changes {
+			got[c.subject] = append(got[c.subject], string(ch.op)+ch.name)
+		}
+	}
+	for subject, want := range map[string][]string{"side": {"+b"}, "main": {"+y"}} {
+		if !slices.Equal(got[subject], want) {
+			t.Errorf("%s changes = %q, want %q", subject, got[subject], want)
+		}
+	}
+}
+
+func TestBrewChangesComparesEntries(t *testing.T) {
+	old := bfile.Parse([]byte(`## Media
+brew "ffmpeg"
+# Audio tagging
+brew "id3v2"
+
+## Casks
+cask "firefox"
+`))
+	new := bfile.Parse([]byte(`## Media
+# Re-sorted, and a comment moved: not a package change.
+brew "ffmpeg"
+brew "yt-dlp"
+
+## Casks
+cask "firefox", greedy: true
+`))
+	var got []string
+	for _, ch := range brewChanges(old, new) {
+		got = append(got, string(ch.op)+" "+ch.name+" "+ch.sec)
+	}
+	want := []string{"+ yt-dlp Media", "~ firefox Casks", "- id3v2 Media"}
+	if !slices.Equal(got, want) {
+		t.Errorf("changes = %q, want %q", got, want)
+	}
+}
+
+func TestRevertSingleChange(t *testing.T) {
+	m := fixtureModel(t)
+	orig := slices.Clone(m.bf.lines)
+	old := bfile.Parse([]byte(`## Media
+brew "ffmpeg"
+# Audio tagging
+brew "id3v2"
+brew "yt-dlp"
+
+## Casks
+cask "firefox"
+cask "vlc"
+`))
+	c := brewCommit{short: "abc1234", subject: "Tidy", changes: brewChanges(old, m.bf.doc)}
+	m.commits = []brewCommit{c}
+	m.state = stateGitLog
+	m = press(m, "enter")
+	if m.state != stateGitChanges {
+		t.Fatalf("state = %v", m.state)
+	}
+
+	// The changes are: tap added, firefox made greedy, id3v2 removed.
+	var ops []string
+	for _, ch := range c.changes {
+		ops = append(ops, string(ch.op)+" "+ch.name)
+	}
+	if want := []string{"+ sevmorris/tap", "~ firefox", "- id3v2"}; !slices.Equal(ops, want) {
+		t.Fatalf("changes = %q, want %q", ops, want)
+	}
+
+	m = press(m, "j", "j", "r")
+	id3 := m.bf.doc.Find(kindBrew, "id3v2")
+	if id3 == nil || m.bf.sectionOf(id3).Name != "Media" || !slices.Equal(id3.Doc, []string{"Audio tagging"}) {
+		t.Fatalf("id3v2 not restored with its comment: %+v (flash %q)", id3, m.flash)
+	}
+	m = press(m, "k", "r")
+	if ff := m.bf.doc.Find(kindCask, "firefox"); ff == nil || ff.Greedy() {
+		t.Errorf("firefox change not reverted: %q", m.flash)
+	}
+	m = press(m, "r")
+	if m.flash != `"firefox" already matches the old line` {
+		t.Errorf("second revert flash = %q", m.flash)
+	}
+
+	m = press(m, "u", "u")
+	if !slices.Equal(m.bf.lines, orig) {
+		t.Errorf("after undo: %q", m.bf.lines)
+	}
+}
@@ -5,9 +5,12 @@ go 1.22
 require (
 	github.com/charmbracelet/bubbletea v1.1.0
 	github.com/charmbracelet/lipgloss v1.0.0
+	mrk-brewfile v0.0.0
 	mrk-theme v0.0.0
 )
 
+replace mrk-brewfile => ../brewfile
+
 replace mrk-theme => ../theme
 
 require (
@@ -0,0 +1,76 @@
+package main
+
+import "slices"
+
+// ── Undo history ──────────────────────────────────────────────────────────
+
+// maxHistory bounds the undo stack; a Brewfile is small, but a long session
+// of edits shouldn't grow without limit.
+const maxHistory = 200
+
+// snapshot is the Brewfile text and cursor before (undo) or after (redo) one
+// mutation, with a description of the mutation for the flash line.
+type snapshot struct {
+	desc   string
+	lines  []string
+	secIdx int
+	entIdx int
+}
+
+type history struct {
+	undo []snapshot
+	redo []snapshot
+}
+
+// snapshot captures the current lines and cursor. lines is copied because
+// the edit helpers splice bf.lines in place.
+func (m model) snapshot(desc string) snapshot {
+	return snapshot{desc: desc, lines: slices.Clone(m.bf.lines), secIdx: m.secIdx, entIdx: m.entIdx}
+}
+
+// checkpoint records the state before a mutation described by desc. Every
+// edit calls it once, so a batch (prune, multi-line move) undoes as one step.
+func (m *model) checkpoint(desc string) {
+	m.hist.undo = append(m.hist.undo, m.snapshot(desc))
+	if len(m.hist.undo) > maxHistory {
+		m.hist.undo = m.hist.undo[len(m.hist.undo)-maxHistory:]
+	}
+	m.hist.redo = nil
+}
+
+// restore swaps in a snapshot's lines and cursor.
+func (m *model) restore(s snapshot) {
+	m.bf.lines = s.lines
+	m.bf.reload()
+	m.secIdx, m.entIdx = s.secIdx, s.entIdx
+	m.clampCursor()
+	m.dirty = m.bf.modified()
+}
+
+func (m model) undo() model {
+	n := len(m.hist.undo)
+	if n == 0 {
+		m.flash = "nothing to undo"
+		return m
+	}
+	prev := m.hist.undo[n-1]
+	m.hist.undo = m.hist.undo[:n-1]
+	m.hist.redo = append(m.hist.redo, m.snapshot(prev.desc))
+	m.restore(prev)
+	m.flash = "undid: " + prev.desc
+	return m
+}
+
+func (m model) redo() model {
+	n := len(m.hist.redo)
+	if n == 0 {
+		m.flash = "nothing to redo"
+		return m
+	}
+	next := m.hist.redo[n-1]
+	m.hist.redo = m.hist.redo[:n-1]
+	m.hist.undo = append(m.hist.undo, m.snapshot(next.desc))
+	m.restore(next)
+	m.flash = "redid: " + next.desc
+	return m
+}
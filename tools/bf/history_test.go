@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func fixtureModel(t *testing.T) model {
+	t.Helper()
+	return fixtureModelOf(t, cliFixture)
+}
+
+func fixtureModelOf(t *testing.T, text string) model {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "Brewfile")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bf, err := loadBrewfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newModel(bf)
+}
+
+func keyMsg(k string) tea.KeyMsg {
+	switch k {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "ctrl+r":
+		return tea.KeyMsg{Type: tea.KeyCtrlR}
+	case "up":
+		return tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+// press feeds keys to the model the way Bubble Tea would.
+func press(m model, keys ...string) model {
+	for _, k := range keys {
+		m, _ = m.handleKey(keyMsg(k))
+	}
+	return m
+}
+
+func TestUndoRedoRestoresLinesAndDirty(t *testing.T) {
+	m := fixtureModel(t)
+	orig := slices.Clone(m.bf.lines)
+
+	// Select ffmpeg in CLI Tools, delete it, then toggle greedy on vlc.
+	m = press(m, "j", "l", "d", "y")
+	if m.bf.doc.Find(kindBrew, "ffmpeg") != nil || !m.dirty {
+		t.Fatalf("delete did not apply: dirty=%v", m.dirty)
+	}
+	m = press(m, "h", "j", "l", "j", "g")
+	if vlc := m.bf.doc.Find(kindCask, "vlc"); vlc == nil || !vlc.Greedy() {
+		t.Fatal("greedy toggle did not apply")
+	}
+	afterBoth := slices.Clone(m.bf.lines)
+
+	m = press(m, "u")
+	if m.flash != `undid: toggle greedy on "vlc"` {
+		t.Errorf("flash = %q", m.flash)
+	}
+	m = press(m, "u")
+	if !slices.Equal(m.bf.lines, orig) || m.dirty {
+		t.Errorf("after two undos: dirty=%v lines=%q", m.dirty, m.bf.lines)
+	}
+	if m = press(m, "u"); m.flash != "nothing to undo" {
+		t.Errorf("flash = %q", m.flash)
+	}
+
+	m = press(m, "ctrl+r", "ctrl+r")
+	if !slices.Equal(m.bf.lines, afterBoth) || !m.dirty {
+		t.Errorf("after two redos: dirty=%v lines=%q", m.dirty, m.bf.lines)
+	}
+
+	// A fresh edit drops the redo stack.
+	m = press(m, "u", "g")
+	if m = press(m, "ctrl+r"); m.flash != "nothing to redo" {
+		t.Errorf("flash = %q", m.flash)
+	}
+}
+
+func TestPruneBatchUndoesAsOneStep(t *testing.T) {
+	m := fixtureModel(t)
+	orig := slices.Clone(m.bf.lines)
+
+	var list []pruneEntry
+	for _, e := range m.bf.doc.Entries() {
+		if e.Kind == kindCask {
+			list = append(list, pruneEntry{name: e.Name, kind: e.Kind, marked: true})
+		}
+	}
+	m.pruneList = list
+	m.state = statePrune
+	m = press(m, "enter")
+	if m.bf.doc.Find(kindCask, "firefox") != nil || m.bf.doc.Find(kindCask, "vlc") != nil {
+		t.Fatal("prune did not remove marked casks")
+	}
+
+	m = press(m, "u")
+	if !slices.Equal(m.bf.lines, orig) {
+		t.Errorf("undo after prune: %q", m.bf.lines)
+	}
+	if m.flash != "undid: prune 2 entry/entries" {
+		t.Errorf("flash = %q", m.flash)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	theme "mrk-theme"
+)
+
+// ── Lint ──────────────────────────────────────────────────────────────────
+
+// lintIssue is one finding, tied to the line it is about (0-based).
+type lintIssue struct {
+	Line  int
+	Check string
+	Msg   string
+}
+
+// knownOptions are the option keys brew bundle accepts for each kind.
+var knownOptions = map[pkgKind][]string{
+	kindTap:  {"force_auto_update"},
+	kindBrew: {"args", "conflicts_with", "link", "postinstall", "restart_service", "start_service", "version_file"},
+	kindCask: {"args", "greedy", "postinstall"},
+	kindMas:  {"id"},
+}
+
+// sortedEntries is the order lint expects: kinds grouped in the order they
+// first appear in the section, then names in the order insertLine uses.
+func sortedEntries(s *section) []*entry {
+	rank := map[pkgKind]int{}
+	for _, e := range s.Entries {
+		if _, ok := rank[e.Kind]; !ok {
+			rank[e.Kind] = len(rank)
+		}
+	}
+	out := slices.Clone(s.Entries)
+	slices.SortStableFunc(out, func(a, b *entry) int {
+		if a.Kind != b.Kind {
+			return rank[a.Kind] - rank[b.Kind]
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return out
+}
+
+// lint checks the Brewfile. tapNames maps a tap to the formula and cask
+// names it provides; a tap missing from it is not checked for dependents.
+func (bf *brewfile) lint(tapNames map[string][]string) []lintIssue {
+	var issues []lintIssue
+	add := func(line int, check, format string, a ...any) {
+		issues = append(issues, lintIssue{Line: line, Check: check, Msg: fmt.Sprintf(format, a...)})
+	}
+
+	seen := map[entryKey]*entry{}
+	for _, s := range bf.sections {
+		if s.Line >= 0 && len(s.Entries) == 0 {
+			add(s.Line, "empty", "section %q has no entries", s.Name)
+		}
+		if want := sortedEntries(s); !slices.Equal(want, s.Entries) {
+			i := 0
+			for want[i] == s.Entries[i] {
+				i++
+			}
+			line := s.Line
+			if line < 0 {
+				line = s.Entries[0].Line
+			}
+			add(line, "sort", "section %q is not sorted: %q belongs before %q", s.Name, want[i].Name, s.Entries[i].Name)
+		}
+		for _, e := range s.Entries {
+			k := entryKey{e.Kind, strings.ToLower(e.Name)}
+			if first, dup := seen[k]; dup {
+				add(e.Line, "duplicate", "%s %q is also at line %d", e.Kind, e.Name, first.Line+1)
+			} else {
+				seen[k] = e
+			}
+			if e.Greedy() && e.Kind != kindCask {
+				add(e.Line, "greedy", "%s %q: greedy only applies to casks", e.Kind, e.Name)
+			}
+			for _, o := range e.Options {
+				if o.Key == "greedy" || slices.Contains(knownOptions[e.Kind], o.Key) {
+					continue
+				}
+				add(e.Line, "option", "%s %q: unknown option %q", e.Kind, e.Name, o.Key)
+			}
+		}
+	}
+
+	for _, t := range bf.doc.Entries() {
+		if t.Kind != kindTap {
+			continue
+		}
+		provides, known := tapNames[t.Name]
+		if !known || tapUsed(bf, t.Name, provides) {
+			continue
+		}
+		add(t.Line, "tap", "tap %q: no entry installs from it", t.Name)
+	}
+
+	slices.SortStableFunc(issues, func(a, b lintIssue) int { return a.Line - b.Line })
+	return issues
+}
+
+// tapUsed reports whether an entry names tap's formulae or casks, either
+// qualified ("owner/repo/name") or by a name the tap provides.
+func tapUsed(bf *brewfile, tap string, provides []string) bool {
+	prefix := strings.ToLower(tap) + "/"
+	for _, e := range bf.doc.Entries() {
+		if e.Kind != kindBrew && e.Kind != kindCask {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(e.Name), prefix) || slices.Contains(provides, e.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortSection reorders s's entries into sortedEntries order. Each entry
+// carries its annotation; blank lines and loose comments between entries
+// stay where they are. It reports whether anything moved.
+func (bf *brewfile) sortSection(s *section) bool {
+	want := sortedEntries(s)
+	if slices.Equal(want, s.Entries) {
+		return false
+	}
+	ents := s.Entries
+	var region []string
+	for i, e := range want {
+		region = append(region, bf.lines[e.DocLine:e.Line+1]...)
+		if i < len(ents)-1 {
+			region = append(region, bf.lines[ents[i].Line+1:ents[i+1].DocLine]...)
+		}
+	}
+	bf.splice(ents[0].DocLine, ents[len(ents)-1].Line+1, region)
+	return true
+}
+
+// unsorted reports whether any section is out of order.
+func (bf *brewfile) unsorted() bool {
+	for _, s := range bf.sections {
+		if !slices.Equal(sortedEntries(s), s.Entries) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortAll sorts every section and returns how many changed.
+func (bf *brewfile) sortAll() int {
+	n := 0
+	for i := range bf.sections {
+		// sortSection reloads, so look the section up again each time.
+		if bf.sortSection(bf.sections[i]) {
+			n++
+		}
+	}
+	return n
+}
+
+// ── Tap contents ──────────────────────────────────────────────────────────
+
+// TapNames lists the formulae and casks in a tapped tap's checkout, found
+// under $(brew --repository)/Library/Taps.
+func (b *execBrew) TapNames(tap string) ([]string, error) {
+	dir, err := b.tapDir(tap)
+	if err != nil {
+		return nil, err
+	}
+	return tapDirNames(dir), nil
+}
+
+// tapDir returns the checkout of a tapped tap.
+func (b *execBrew) tapDir(tap string) (string, error) {
+	owner, repo, ok := strings.Cut(strings.ToLower(tap), "/")
+	if !ok {
+		return "", fmt.Errorf("bad tap name %q", tap)
+	}
+	root := os.Getenv("HOMEBREW_REPOSITORY")
+	if root == "" {
+		out, err := exec.Command(b.bin, "--repository").Output()
+		if err != nil {
+			return "", err
+		}
+		root = strings.TrimSpace(string(out))
+	}
+	dir := filepath.Join(root, "Library", "Taps", owner, "homebrew-"+strings.TrimPrefix(repo, "homebrew-"))
+	if _, err := os.Stat(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// tapDirNames collects the .rb names in a tap's Formula, HomebrewFormula and
+// Casks directories, or at its top level for old-style taps.
+func tapDirNames(dir string) []string {
+	var names []string
+	collect := func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(d.Name(), ".rb") {
+			names = append(names, strings.TrimSuffix(d.Name(), ".rb"))
+		}
+		return nil
+	}
+	for _, sub := range []string{"Formula", "HomebrewFormula", "Casks"} {
+		filepath.WalkDir(filepath.Join(dir, sub), collect)
+	}
+	if top, err := filepath.Glob(filepath.Join(dir, "*.rb")); err == nil {
+		for _, p := range top {
+			names = append(names, strings.TrimSuffix(filepath.Base(p), ".rb"))
+		}
+	}
+	return names
+}
+
+// tapNamesFor asks brew about every tap in the Brewfile. Taps it cannot
+// read are left out, so lint does not guess about them.
+func tapNamesFor(brew brewBackend, bf *brewfile) map[string][]string {
+	out := map[string][]string{}
+	if brew == nil {
+		return out
+	}
+	for _, e := range bf.doc.Entries() {
+		if e.Kind != kindTap {
+			continue
+		}
+		if names, err := brew.TapNames(e.Name); err == nil {
+			out[e.Name] = names
+		}
+	}
+	return out
+}
+
+// ── Lint view ─────────────────────────────────────────────────────────────
+
+type lintTapsMsg map[string][]string
+
+func fetchLintTaps(brew brewBackend, bf *brewfile) tea.Cmd {
+	return func() tea.Msg { return lintTapsMsg(tapNamesFor(brew, bf)) }
+}
+
+// relint refreshes the lint view after an edit.
+func (m model) relint() model {
+	m.lintIssues = m.bf.lint(m.lintTaps)
+	m.lintIdx = min(m.lintIdx, max(0, len(m.lintIssues)-1))
+	return m
+}
+
+func (m model) handleLint(key string) (model, tea.Cmd) {
+	m.flash = ""
+	switch key {
+	case "esc", "q":
+		m.state = stateNormal
+	case "up", "k":
+		if m.lintIdx > 0 {
+			m.lintIdx--
+		}
+	case "down", "j":
+		if m.lintIdx < len(m.lintIssues)-1 {
+			m.lintIdx++
+		}
+	case "enter":
+		if m.lintIdx < len(m.lintIssues) {
+			m = m.jumpToLine(m.lintIssues[m.lintIdx].Line)
+			m.state = stateNormal
+		}
+	case "s":
+		if !m.bf.unsorted() {
+			m.flash = "already sorted"
+			break
+		}
+		m.checkpoint("sort sections")
+		n := m.bf.sortAll()
+		m.dirty = true
+		m.clampCursor()
+		m = m.relint()
+		m.flash = fmt.Sprintf("sorted %d section(s)", n)
+	}
+	return m, nil
+}
+
+// jumpToLine selects the entry or section header on line.
+func (m model) jumpToLine(line int) model {
+	for _, e := range m.bf.doc.Entries() {
+		if e.Line == line {
+			return m.jumpTo(e)
+		}
+	}
+	for i, s := range m.bf.sections {
+		if s.Line == line {
+			m.secIdx, m.entIdx = i, 0
+			m.leftFocus = true
+		}
+	}
+	return m
+}
+
+func (m model) viewLint(bodyH int) string {
+	inner := m.width - 4
+	paneH := max(bodyH-2, 1)
+
+	if m.lintLoading {
+		return theme.StylePaneOn.Width(inner).Height(paneH).Render(styleDim.Render("checking taps…"))
+	}
+	if len(m.lintIssues) == 0 {
+		return theme.StylePaneOn.Width(inner).Height(paneH).
+			Render(styleFlash.Render("✓ no lint issues"))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(styleInputPfx.Render(fmt.Sprintf(" lint › %d issue(s)", len(m.lintIssues))) + "\n\n")
+	listH := max(paneH-2, 1)
+	start := 0
+	if m.lintIdx >= listH {
+		start = m.lintIdx - listH + 1
+	}
+	for i, is := range m.lintIssues[start:min(len(m.lintIssues), start+listH)] {
+		i += start
+		loc := padRight(fmt.Sprintf("%d", is.Line+1), 5)
+		check := styleOutdated.Render(padRight(is.Check, 10))
+		msg := theme.Truncate(is.Msg, max(1, inner-20))
+		if i == m.lintIdx {
+			sb.WriteString(styleEntCursor.Render("▸ "+loc) + check + styleEntCursor.Render(msg) + "\n")
+		} else {
+			sb.WriteString("  " + styleDim.Render(loc) + check + styleEntNorm.Render(msg) + "\n")
+		}
+	}
+	return theme.StylePaneOn.Width(inner).Height(paneH).Render(strings.TrimRight(sb.String(), "\n"))
+}
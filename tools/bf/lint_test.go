@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lintFixture = `## Taps
+tap "other/tools"
+tap "sevmorris/tap"
+
+## CLI Tools
+brew "bat"
+# needed by the backup script
+brew "bash", link: true
+brew "autoconf", greedy: true
+
+brew "flac", hot: yes
+cask "zed"
+
+## Empty
+
+## Casks
+cask "firefox", greedy: true
+brew "bat"
+`
+
+func TestLintReportsEachCheck(t *testing.T) {
+	m := fixtureModelOf(t, lintFixture)
+	taps := map[string][]string{"sevmorris/tap": {"mrk-helper"}}
+
+	var got []string
+	for _, is := range m.bf.lint(taps) {
+		got = append(got, fmt.Sprintf("%d %s %s", is.Line+1, is.Check, is.Msg))
+	}
+	want := []string{
+		`3 tap tap "sevmorris/tap": no entry installs from it`,
+		`5 sort section "CLI Tools" is not sorted: "autoconf" belongs before "bat"`,
+		`9 greedy brew "autoconf": greedy only applies to casks`,
+		`11 option brew "flac": unknown option "hot"`,
+		`14 empty section "Empty" has no entries`,
+		`18 duplicate brew "bat" is also at line 6`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lint:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSortKeepsCommentsAndGaps(t *testing.T) {
+	m := fixtureModelOf(t, lintFixture)
+	m.lintTaps = map[string][]string{}
+	m.state = stateLint
+	m = m.relint()
+
+	m = press(m, "s")
+	want := `## CLI Tools
+brew "autoconf", greedy: true
+# needed by the backup script
+brew "bash", link: true
+brew "bat"
+
+brew "flac", hot: yes
+cask "zed"
+`
+	if got := fixtureText(m); !strings.Contains(got, want) {
+		t.Fatalf("after sort:\n%s", got)
+	}
+	for _, is := range m.lintIssues {
+		if is.Check == "sort" {
+			t.Errorf("still unsorted: %s", is.Msg)
+		}
+	}
+	if m = press(m, "s"); m.flash != "already sorted" {
+		t.Errorf("second sort: flash=%q", m.flash)
+	}
+	if m = press(m, "esc", "u"); m.flash != "undid: sort sections" {
+		t.Errorf("undo: flash=%q", m.flash)
+	}
+}
+
+func TestCLILintFix(t *testing.T) {
+	t.Setenv("HOMEBREW_REPOSITORY", t.TempDir())
+	path := filepath.Join(t.TempDir(), "Brewfile")
+	if err := os.WriteFile(path, []byte(lintFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, code := runFixture(t, path, "lint")
+	if code != 1 || !strings.Contains(out, path+":5: sort:") || strings.Contains(out, ": tap:") {
+		t.Errorf("lint exit %d:\n%s", code, out)
+	}
+	out, code = runFixture(t, path, "lint", "--fix")
+	if code != 1 || !strings.HasPrefix(out, "sorted 1 section(s)\n") || strings.Contains(out, ": sort:") {
+		t.Errorf("lint --fix exit %d:\n%s", code, out)
+	}
+
+	clean := filepath.Join(t.TempDir(), "Brewfile")
+	if err := os.WriteFile(clean, []byte(cliFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runFixture(t, clean, "lint"); code != 0 || out != "" {
+		t.Errorf("clean Brewfile: exit %d:\n%s", code, out)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	bfile "mrk-brewfile"
+)
+
+// ── Lock file ─────────────────────────────────────────────────────────────
+
+func (b *execBrew) LockInfo() (map[entryKey]bfile.LockEntry, error) {
+	if _, err := exec.LookPath(b.bin); err != nil {
+		return nil, fmt.Errorf("%s not found", b.bin)
+	}
+	out, err := exec.Command(b.bin, "info", "--json=v2", "--installed").Output()
+	if err != nil {
+		return nil, fmt.Errorf("brew info --installed: %w", err)
+	}
+	info, err := parseLockInfo(out)
+	if err != nil {
+		return nil, err
+	}
+	if out, err := exec.Command(b.bin, "tap").Output(); err == nil {
+		for _, tap := range strings.Fields(string(out)) {
+			le := bfile.LockEntry{Name: tap, Kind: kindTap.String()}
+			// Taps served from the API have no checkout to pin.
+			if dir, err := b.tapDir(tap); err == nil {
+				if rev, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output(); err == nil {
+					le.Revision = strings.TrimSpace(string(rev))
+				}
+			}
+			info[entryKey{kindTap, tap}] = le
+		}
+	}
+	return info, nil
+}
+
+// parseLockInfo reads `brew info --json=v2 --installed`. A formula's version
+// is its newest installed keg, which carries the revision suffix the way
+// `brew list --versions` prints it.
+func parseLockInfo(data []byte) (map[entryKey]bfile.LockEntry, error) {
+	var raw brewInfoJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("brew info: %w", err)
+	}
+	info := map[entryKey]bfile.LockEntry{}
+	for _, f := range raw.Formulae {
+		if len(f.Installed) == 0 {
+			continue
+		}
+		le := bfile.LockEntry{Name: f.Name, Kind: kindBrew.String(), Tap: f.Tap,
+			Version: f.Installed[len(f.Installed)-1].Version}
+		if f.Revision > 0 {
+			le.Revision = strconv.Itoa(f.Revision)
+		}
+		info[entryKey{kindBrew, f.Name}] = le
+	}
+	for _, c := range raw.Casks {
+		if c.Installed == "" {
+			continue
+		}
+		info[entryKey{kindCask, c.Token}] = bfile.LockEntry{Name: c.Token, Kind: kindCask.String(),
+			Tap: c.Tap, Version: c.Installed}
+	}
+	return info, nil
+}
+
+// buildLock records the installed state of the Brewfile's taps, formulae and
+// casks, in file order and under the names the Brewfile uses. Entries for
+// other profiles keep what prev locked for them, so machines with different
+// profiles can share one lock file; anything else not installed is dropped.
+func buildLock(bf *brewfile, prev *bfile.Lock, info map[entryKey]bfile.LockEntry, profile string) *bfile.Lock {
+	lock := &bfile.Lock{}
+	seen := map[entryKey]bool{}
+	for _, e := range bf.doc.Entries() {
+		if e.Kind != kindTap && e.Kind != kindBrew && e.Kind != kindCask || seen[keyOf(e)] {
+			continue
+		}
+		seen[keyOf(e)] = true
+		if !e.InProfile(profile) {
+			if prev != nil {
+				if le, ok := prev.Find(e.Kind, e.Name); ok {
+					lock.Entries = append(lock.Entries, le)
+				}
+			}
+			continue
+		}
+		name := e.Name
+		if e.Kind != kindTap {
+			name = name[strings.LastIndex(name, "/")+1:]
+		}
+		if le, ok := info[entryKey{e.Kind, name}]; ok {
+			le.Name = e.Name
+			lock.Entries = append(lock.Entries, le)
+		}
+	}
+	return lock
+}
+
+// lockedVersion is the version the lock file records for e, or "".
+func (bf *brewfile) lockedVersion(e *entry) string {
+	if bf.lock == nil {
+		return ""
+	}
+	le, _ := bf.lock.Find(e.Kind, e.Name)
+	return le.Version
+}
+
+func cliLock(args []string, stdout, stderr io.Writer) error {
+	f := newCLIFlags("lock", stderr)
+	check := f.fs.Bool("check", false, "report entries whose installed version differs from the lock instead of writing it")
+	profile := f.fs.String("profile", bfile.ActiveProfile(), "profile this machine installs")
+	if _, err := f.parse(args); err != nil {
+		return err
+	}
+
+	bf, err := loadBrewfile(f.file)
+	if err != nil {
+		return err
+	}
+	// loadBrewfile skips a lock it cannot read; here that is an error.
+	if bf.lock, err = loadLock(bf.path); err != nil {
+		return err
+	}
+	path := bfile.LockPath(bf.path)
+	brew := newExecBrew()
+
+	if *check {
+		if bf.lock == nil {
+			return fmt.Errorf("no %s — run bf lock first", bfile.LockName)
+		}
+		st, err := brew.Installed()
+		if err != nil {
+			return err
+		}
+		drift := bf.lock.ForProfile(bf.doc, strings.ToLower(*profile)).Drift(func(kind pkgKind, name string) (string, bool) {
+			s, installed, _ := st.of(bfile.NewEntry(kind, name))
+			return s.Version, installed
+		})
+		for _, d := range drift {
+			installed := d.Installed
+			if installed == "" {
+				installed = "not installed"
+			}
+			fmt.Fprintf(stdout, "%s %s: locked %s, installed %s\n", d.Kind, d.Name, d.Version, installed)
+		}
+		if len(drift) > 0 {
+			return fmt.Errorf("%d entries differ from %s", len(drift), bfile.LockName)
+		}
+		return nil
+	}
+
+	info, err := brew.LockInfo()
+	if err != nil {
+		return err
+	}
+	lock := buildLock(bf, bf.lock, info, strings.ToLower(*profile))
+	if err := lock.WriteFile(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "locked %d entries → %s\n", len(lock.Entries), path)
+	return nil
+}
+
+// loadLock reads the lock file next to the Brewfile; a missing one is not
+// an error.
+func loadLock(brewfilePath string) (*bfile.Lock, error) {
+	lock, err := bfile.LoadLock(bfile.LockPath(brewfilePath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return lock, err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	bfile "mrk-brewfile"
+)
+
+func TestParseLockInfo(t *testing.T) {
+	out := []byte(`{
+  "formulae": [
+    {"name": "ffmpeg", "tap": "homebrew/core", "revision": 1,
+     "installed": [{"version": "7.0_1"}, {"version": "7.1_1"}]},
+    {"name": "bf", "tap": "sevmorris/tap", "revision": 0, "installed": [{"version": "1.2.0"}]},
+    {"name": "gone", "tap": "homebrew/core", "installed": []}
+  ],
+  "casks": [
+    {"token": "firefox", "tap": "homebrew/cask", "installed": "130.0"},
+    {"token": "never", "tap": "homebrew/cask", "installed": null}
+  ]
+}`)
+	got, err := parseLockInfo(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[entryKey]bfile.LockEntry{
+		{kindBrew, "ffmpeg"}:  {Name: "ffmpeg", Kind: "brew", Version: "7.1_1", Tap: "homebrew/core", Revision: "1"},
+		{kindBrew, "bf"}:      {Name: "bf", Kind: "brew", Version: "1.2.0", Tap: "sevmorris/tap"},
+		{kindCask, "firefox"}: {Name: "firefox", Kind: "cask", Version: "130.0", Tap: "homebrew/cask"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLockInfo = %+v", got)
+	}
+}
+
+func TestBuildLockKeepsOtherProfiles(t *testing.T) {
+	m := fixtureModelOf(t, `tap "sevmorris/tap"
+brew "sevmorris/tap/bf"
+brew "ffmpeg"
+brew "yt-dlp"
+cask "reaper"  # @profile studio
+`)
+	info := map[entryKey]bfile.LockEntry{
+		{kindTap, "sevmorris/tap"}: {Name: "sevmorris/tap", Kind: "tap", Revision: "abc123"},
+		{kindBrew, "bf"}:           {Name: "bf", Kind: "brew", Version: "1.2.0", Tap: "sevmorris/tap"},
+		{kindBrew, "ffmpeg"}:       {Name: "ffmpeg", Kind: "brew", Version: "7.1_1", Tap: "homebrew/core"},
+	}
+	prev := &bfile.Lock{Entries: []bfile.LockEntry{
+		{Name: "reaper", Kind: "cask", Version: "7.0", Tap: "homebrew/cask"},
+		{Name: "yt-dlp", Kind: "brew", Version: "2024.01.01"},
+	}}
+
+	lock := buildLock(m.bf, prev, info, "laptop")
+	var got []string
+	for _, le := range lock.Entries {
+		got = append(got, le.Kind+" "+le.Name+" "+le.Version)
+	}
+	// yt-dlp is not installed here, so its old lock goes; reaper is another
+	// machine's and keeps it.
+	want := []string{"tap sevmorris/tap ", "brew sevmorris/tap/bf 1.2.0", "brew ffmpeg 7.1_1", "cask reaper 7.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lock = %q, want %q", got, want)
+	}
+}
+
+func TestDetailShowsLockDrift(t *testing.T) {
+	m := fixtureModel(t)
+	m.status = &installedState{
+		Formulae: map[string]pkgState{"ffmpeg": {Version: "7.1_1"}, "yt-dlp": {Version: "2025.01.01"}},
+		Casks:    map[string]pkgState{},
+		Taps:     map[string]bool{},
+	}
+	m.bf.lock = &bfile.Lock{Entries: []bfile.LockEntry{
+		{Name: "ffmpeg", Kind: "brew", Version: "7.0_2"},
+		{Name: "yt-dlp", Kind: "brew", Version: "2025.01.01"},
+	}}
+	if got := m.statusDetail(m.bf.doc.Find(kindBrew, "ffmpeg")); !strings.Contains(got, "7.1_1 ≠ lock 7.0_2") {
+		t.Errorf("ffmpeg detail = %q", got)
+	}
+	if got := m.statusDetail(m.bf.doc.Find(kindBrew, "yt-dlp")); got != "" {
+		t.Errorf("yt-dlp detail = %q", got)
+	}
+}
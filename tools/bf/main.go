@@ -4,17 +4,17 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	bfile "mrk-brewfile"
 	theme "mrk-theme"
 )
 
@@ -26,140 +26,70 @@ var (
 
 // ── Types ─────────────────────────────────────────────────────────────────
 
-type pkgKind int
+// Brewfile structure comes from the shared mrk-brewfile parser; bf works on
+// its entries and sections directly.
+type (
+	pkgKind = bfile.Kind
+	entry   = bfile.Entry
+	section = bfile.Section
+)
 
 const (
-	kindBrew pkgKind = iota
-	kindCask
+	kindTap       = bfile.KindTap
+	kindBrew      = bfile.KindBrew
+	kindCask      = bfile.KindCask
+	kindMas       = bfile.KindMas
+	kindVSCode    = bfile.KindVSCode
+	kindWhalebrew = bfile.KindWhalebrew
 )
 
-func (k pkgKind) String() string {
-	if k == kindCask {
-		return "cask"
-	}
-	return "brew"
-}
-
-type entry struct {
-	lineIdx int
-	name    string
-	kind    pkgKind
-	greedy  bool
-}
+// addKinds is the order the add flow offers directives in.
+var addKinds = []pkgKind{kindBrew, kindCask, kindTap, kindMas, kindVSCode, kindWhalebrew}
 
-type section struct {
-	name          string
-	fullName      string // original comment text
-	headerLineIdx int    // -1 if implicit
-	entries       []*entry
+// optionSummary renders an entry's positional args and options compactly for
+// the package list. greedy has its own ◆ column and is left out.
+func optionSummary(e *entry) string {
+	parts := append([]string(nil), e.Args...)
+	for _, o := range e.Options {
+		if o.Key == "greedy" && o.Value == "true" {
+			continue
+		}
+		parts = append(parts, o.String())
+	}
+	return strings.Join(parts, ", ")
 }
 
 // ── Brewfile ──────────────────────────────────────────────────────────────
 
-var (
-	reFormula = regexp.MustCompile(`^brew\s+"([^"]+)"(.*)$`)
-	reCask    = regexp.MustCompile(`^cask\s+"([^"]+)"(.*)$`)
-	reHeader  = regexp.MustCompile(`^##\s+(.+)$`)
-	reGreedy  = regexp.MustCompile(`,\s*greedy:\s*true`)
-)
-
 type brewfile struct {
 	path     string
 	repoRoot string
 	lines    []string
+	saved    []string  // lines as last loaded or written
+	stamp    diskStamp // the file as last loaded or written
+	doc      *bfile.File
 	sections []*section
+	lock     *bfile.Lock // Brewfile.lock.json, nil when there is none
 }
 
 func loadBrewfile(path string) (*brewfile, error) {
-	f, err := os.Open(path)
+	bf := &brewfile{path: path, repoRoot: filepath.Dir(path)}
+	doc, st, err := bf.readDisk()
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	bf := &brewfile{
-		path:     path,
-		repoRoot: filepath.Dir(path),
-		lines:    lines,
-	}
-	bf.sections = parseLines(lines)
+	bf.adopt(doc, st)
+	// A broken lock only hides the lock column; bf lock reports it.
+	bf.lock, _ = loadLock(path)
 	return bf, nil
 }
 
-func sectionFriendlyName(s string) string {
-	// "CLI Tools - General Utilities & Power User Tools" → "CLI Tools"
-	if idx := strings.Index(s, " - "); idx != -1 {
-		s = s[:idx]
-	}
-	if idx := strings.Index(s, " — "); idx != -1 {
-		s = s[:idx]
-	}
-	return strings.TrimSpace(s)
-}
-
-func parseLines(lines []string) []*section {
-	var sections []*section
-	var cur *section
-
-	push := func(e *entry) {
-		if cur == nil {
-			cur = &section{name: "General", fullName: "General", headerLineIdx: -1}
-			sections = append(sections, cur)
-		}
-		cur.entries = append(cur.entries, e)
-	}
-
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		if m := reHeader.FindStringSubmatch(trimmed); m != nil {
-			fullName := strings.TrimSpace(m[1])
-			cur = &section{
-				name:          sectionFriendlyName(fullName),
-				fullName:      fullName,
-				headerLineIdx: i,
-			}
-			sections = append(sections, cur)
-			continue
-		}
-
-		if strings.HasPrefix(trimmed, "#") {
-			continue
-		}
-
-		if m := reFormula.FindStringSubmatch(trimmed); m != nil {
-			push(&entry{
-				lineIdx: i,
-				name:    m[1],
-				kind:    kindBrew,
-				greedy:  strings.Contains(m[2], "greedy"),
-			})
-			continue
-		}
-
-		if m := reCask.FindStringSubmatch(trimmed); m != nil {
-			push(&entry{
-				lineIdx: i,
-				name:    m[1],
-				kind:    kindCask,
-				greedy:  strings.Contains(m[2], "greedy"),
-			})
-		}
-	}
-
-	// Drop empty sections
-	out := sections[:0]
-	for _, s := range sections {
-		if len(s.entries) > 0 {
+// visibleSections lists the sections bf shows: every "## " header, even an
+// empty one, plus the implicit leading section when it has entries.
+func visibleSections(doc *bfile.File) []*section {
+	var out []*section
+	for _, s := range doc.Sections {
+		if s.Line >= 0 || len(s.Entries) > 0 {
 			out = append(out, s)
 		}
 	}
@@ -167,104 +97,150 @@ func parseLines(lines []string) []*section {
 }
 
 func (bf *brewfile) reload() {
-	bf.sections = parseLines(bf.lines)
+	doc := bfile.ParseLines(bf.lines)
+	if bf.doc != nil {
+		doc.FinalNewline = bf.doc.FinalNewline
+	}
+	bf.doc = doc
+	bf.sections = visibleSections(doc)
 }
 
+// save writes the Brewfile unless it changed on disk since bf read it, in
+// which case it returns errChangedOnDisk and writes nothing.
 func (bf *brewfile) save() error {
-	out := strings.Join(bf.lines, "\n") + "\n"
-	tmp, err := os.CreateTemp(filepath.Dir(bf.path), ".bf-*.tmp")
+	changed, err := bf.changedOnDisk()
 	if err != nil {
 		return err
 	}
-	if _, err := tmp.WriteString(out); err != nil {
-		tmp.Close()
-		os.Remove(tmp.Name())
-		return err
+	if changed {
+		return errChangedOnDisk
 	}
-	if err := tmp.Close(); err != nil {
-		os.Remove(tmp.Name())
+	return bf.write()
+}
+
+// write saves unconditionally, overwriting any external change.
+func (bf *brewfile) write() error {
+	bf.doc.Lines = bf.lines
+	if err := bf.doc.WriteFile(bf.path); err != nil {
 		return err
 	}
-	return os.Rename(tmp.Name(), bf.path)
+	bf.saved = slices.Clone(bf.lines)
+	if fi, err := os.Stat(bf.path); err == nil {
+		bf.stamp = stampOf(fi, bf.doc.Bytes())
+	}
+	return nil
 }
 
+// modified reports whether lines differ from what is on disk, so undoing
+// back to the saved state clears the dirty marker.
+func (bf *brewfile) modified() bool {
+	return !slices.Equal(bf.lines, bf.saved)
+}
+
+// deleteEntry removes e together with its annotation lines.
 func (bf *brewfile) deleteEntry(e *entry) {
-	idx := e.lineIdx
-	if idx < 0 || idx >= len(bf.lines) {
+	if e.Line < 0 || e.Line >= len(bf.lines) {
 		return
 	}
-	bf.lines = append(bf.lines[:idx], bf.lines[idx+1:]...)
-	bf.reload()
+	bf.splice(e.DocLine, e.Line+1, nil)
 }
 
 func (bf *brewfile) toggleGreedy(e *entry) {
-	if e.kind != kindCask || e.lineIdx < 0 || e.lineIdx >= len(bf.lines) {
+	bf.setGreedy(e, !e.Greedy())
+}
+
+// setGreedy turns greedy: true on or off for a cask.
+func (bf *brewfile) setGreedy(e *entry, on bool) {
+	if e.Kind != kindCask || e.Line < 0 || e.Line >= len(bf.lines) {
 		return
 	}
-	line := bf.lines[e.lineIdx]
-	if e.greedy {
-		line = reGreedy.ReplaceAllString(line, "")
+	if on {
+		e.SetOption("greedy", "true")
 	} else {
-		line = strings.TrimRight(line, " \t") + ", greedy: true"
+		e.DeleteOption("greedy")
 	}
-	bf.lines[e.lineIdx] = line
+	bf.lines[e.Line] = e.Format()
 	bf.reload()
 }
 
-func formatLine(name string, kind pkgKind, greedy bool) string {
-	suffix := ""
-	if greedy && kind == kindCask {
-		suffix = ", greedy: true"
+// setOptions replaces e's option hash and rewrites its line in place.
+func (bf *brewfile) setOptions(e *entry, opts []bfile.Option) {
+	if e.Line < 0 || e.Line >= len(bf.lines) {
+		return
 	}
-	return fmt.Sprintf(`%s "%s"%s`, kind, name, suffix)
+	e.Options = opts
+	bf.lines[e.Line] = e.Format()
+	bf.reload()
 }
 
 // addEntry inserts a new package alphabetically within the named section.
-func (bf *brewfile) addEntry(name string, kind pkgKind, greedy bool, secName string) {
-	newLine := formatLine(name, kind, greedy)
+func (bf *brewfile) addEntry(name string, kind pkgKind, greedy bool, secName string, opts ...bfile.Option) {
+	e := bfile.NewEntry(kind, name, opts...)
+	if greedy && kind == kindCask {
+		e.SetOption("greedy", "true")
+	}
+	bf.insertLine(e.Format(), name, kind, secName)
+}
+
+// insertLine places an already-formatted entry line alphabetically within
+// the named section, or at the end of the file if the section is missing.
+func (bf *brewfile) insertLine(newLine, name string, kind pkgKind, secName string) {
+	bf.insertLines([]string{newLine}, name, kind, secName)
+}
+
+// insertLines is insertLine for an entry that carries annotation lines;
+// block ends with the entry's own line.
+func (bf *brewfile) insertLines(block []string, name string, kind pkgKind, secName string) {
 
 	var target *section
 	for _, s := range bf.sections {
-		if s.name == secName {
+		if s.Name == secName {
 			target = s
 			break
 		}
 	}
 
-	if target == nil || len(target.entries) == 0 {
-		bf.lines = append(bf.lines, newLine)
-		bf.reload()
-		return
-	}
-
-	// Find alphabetical insertion point (within same kind where possible)
-	insertAt := -1
-	for _, e := range target.entries {
-		if e.kind == kind && e.name > name {
-			insertAt = e.lineIdx
-			break
+	var insertAt int
+	switch {
+	case target == nil:
+		insertAt = len(bf.lines)
+	case len(target.Entries) == 0:
+		insertAt = bf.sectionBodyEnd(target)
+	default:
+		// Find alphabetical insertion point (within same kind where possible),
+		// ahead of the following entry's annotation.
+		insertAt = -1
+		for _, e := range target.Entries {
+			if e.Kind == kind && e.Name > name {
+				insertAt = e.DocLine
+				break
+			}
+		}
+		if insertAt == -1 {
+			// After last entry in section
+			last := target.Entries[len(target.Entries)-1]
+			insertAt = last.Line + 1
 		}
-	}
-	if insertAt == -1 {
-		// After last entry in section
-		last := target.entries[len(target.entries)-1]
-		insertAt = last.lineIdx + 1
 	}
 
-	newLines := make([]string, 0, len(bf.lines)+1)
+	newLines := make([]string, 0, len(bf.lines)+len(block))
 	newLines = append(newLines, bf.lines[:insertAt]...)
-	newLines = append(newLines, newLine)
+	newLines = append(newLines, block...)
 	newLines = append(newLines, bf.lines[insertAt:]...)
 	bf.lines = newLines
 	bf.reload()
 }
 
+// moveEntry carries the entry's line verbatim, with the annotation lines
+// above it, so options and comments survive the move.
 func (bf *brewfile) moveEntry(e *entry, targetSec string) {
-	name := e.name
-	kind := e.kind
-	greedy := e.greedy
+	block := bf.entryBlock(e)
+	for i := range block {
+		block[i] = strings.TrimSpace(block[i])
+	}
+	name, kind := e.Name, e.Kind
 	bf.deleteEntry(e)
-	bf.addEntry(name, kind, greedy, targetSec)
+	bf.insertLines(block, name, kind, targetSec)
 }
 
 func (bf *brewfile) commit(msg string) error {
@@ -283,40 +259,6 @@ func (bf *brewfile) commit(msg string) error {
 	return nil
 }
 
-// ── Search ────────────────────────────────────────────────────────────────
-
-type searchResult struct {
-	secIdx int
-	entIdx int
-	name   string
-	sec    string
-	kind   pkgKind
-	greedy bool
-}
-
-func (bf *brewfile) search(query string) []searchResult {
-	if query == "" {
-		return nil
-	}
-	q := strings.ToLower(query)
-	var results []searchResult
-	for si, s := range bf.sections {
-		for ei, e := range s.entries {
-			if strings.Contains(strings.ToLower(e.name), q) {
-				results = append(results, searchResult{
-					secIdx: si,
-					entIdx: ei,
-					name:   e.name,
-					sec:    s.name,
-					kind:   e.kind,
-					greedy: e.greedy,
-				})
-			}
-		}
-	}
-	return results
-}
-
 // ── Descriptions ──────────────────────────────────────────────────────────
 
 type descMsg map[string]string
@@ -327,10 +269,11 @@ func fetchSectionDescs(entries []*entry) tea.Cmd {
 
 		var formulas, casks []string
 		for _, e := range entries {
-			if e.kind == kindBrew {
-				formulas = append(formulas, e.name)
-			} else {
-				casks = append(casks, e.name)
+			switch e.Kind {
+			case kindBrew:
+				formulas = append(formulas, e.Name)
+			case kindCask:
+				casks = append(casks, e.Name)
 			}
 		}
 
@@ -361,64 +304,16 @@ func fetchSectionDescs(entries []*entry) tea.Cmd {
 func (m model) missingDescs(entries []*entry) []*entry {
 	var missing []*entry
 	for _, e := range entries {
-		if _, ok := m.descCache[e.name]; !ok {
+		if e.Kind != kindBrew && e.Kind != kindCask {
+			continue
+		}
+		if _, ok := m.descCache[e.Name]; !ok {
 			missing = append(missing, e)
 		}
 	}
 	return missing
 }
 
-// ── Prune ─────────────────────────────────────────────────────────────────
-
-type pruneEntry struct {
-	name     string
-	kind     pkgKind
-	sec      string
-	marked   bool
-	entryRef *entry // pointer into brewfile for deletion
-}
-
-type pruneMsg []pruneEntry
-
-func fetchPruneList(bf *brewfile) tea.Cmd {
-	return func() tea.Msg {
-		// Get installed packages
-		instF := map[string]bool{}
-		instC := map[string]bool{}
-		if out, err := exec.Command("brew", "list", "--formula").Output(); err == nil {
-			for _, p := range strings.Fields(string(out)) {
-				instF[p] = true
-			}
-		}
-		if out, err := exec.Command("brew", "list", "--cask").Output(); err == nil {
-			for _, p := range strings.Fields(string(out)) {
-				instC[p] = true
-			}
-		}
-		// Find Brewfile entries not installed
-		var uninstalled []pruneEntry
-		for _, sec := range bf.sections {
-			for _, e := range sec.entries {
-				var installed bool
-				if e.kind == kindBrew {
-					installed = instF[e.name]
-				} else {
-					installed = instC[e.name]
-				}
-				if !installed {
-					uninstalled = append(uninstalled, pruneEntry{
-						name:     e.name,
-						kind:     e.kind,
-						sec:      sec.name,
-						entryRef: e,
-					})
-				}
-			}
-		}
-		return pruneMsg(uninstalled)
-	}
-}
-
 // ── TUI State ─────────────────────────────────────────────────────────────
 
 type viewState int
@@ -433,10 +328,30 @@ const (
 	stateDeleteConfirm
 	stateCommit
 	statePrune
+	stateAddMasID
+	stateOptions
+	stateOptionInput
+	stateQuitConfirm
+	stateDiff
+	stateConflict
+	stateAddLookup
+	stateActionConfirm
+	stateSectionName
+	stateSectionMerge
+	stateSectionDeleteConfirm
+	stateNotes
+	stateNoteInput
+	stateLint
+	stateAdopt
+	stateProfileInput
+	stateGitLog
+	stateGitChanges
+	stateDetail
 )
 
 type model struct {
-	bf *brewfile
+	bf   *brewfile
+	brew brewBackend
 
 	// Normal navigation
 	secIdx    int
@@ -446,6 +361,14 @@ type model struct {
 	// Description cache (keyed by package name)
 	descCache map[string]string
 
+	// Installed state, nil until the first fetch returns
+	status          *installedState
+	filterAttention bool // show only missing or outdated entries
+
+	// Profiles: the package pane shows the active profile's entries
+	profile     string // $MRK_PROFILE or the hostname (--profile)
+	allProfiles bool   // show every profile's entries
+
 	// Search
 	searchQuery   string
 	searchResults []searchResult
@@ -456,18 +379,83 @@ type model struct {
 	addKind    pkgKind
 	addKindIdx int
 	addSecIdx  int
-
-	// Move
+	addOpts    []bfile.Option
+	addChecked string // name Homebrew didn't know; entering it again adds anyway
+	addResults []pkgInfo
+	addResIdx  int // highlighted match, or -1 for the typed name
+	addSeq     int // bumps on every keystroke; stale searches are dropped
+
+	// Marks for bulk edits, kept across sections
+	marks        map[entryKey]bool
+	visual       bool
+	visualAnchor int
+
+	// Lint view
+	lintIssues  []lintIssue
+	lintIdx     int
+	lintTaps    map[string][]string
+	lintLoading bool
+
+	// Git history
+	commits    []brewCommit
+	logIdx     int
+	changeIdx  int
+	logLoading bool
+
+	// Package details, cached on disk between runs
+	details         map[string]pkgDetail // keyed by detailKey
+	depGraph        map[string][]string  // installed formulae's deps, for "used by"
+	detailLoading   bool
+	detailErr       string
+	detailCachePath string // ~/Library/Caches/mrk/bf-info.json
+
+	// Option editor
+	optIdx     int
+	optEditIdx int // index being edited, or -1 when adding
+
+	// Comment editor
+	noteIdx     int
+	noteEditIdx int // row being edited, or -1 when adding a line
+
+	// Move (also the merge target when deleting a section)
 	moveSecIdx int
 
-	// Prune
-	pruneList    []pruneEntry
+	// Section editing
+	secRename bool // the section-name prompt renames rather than creates
+
+	// Prune and drift
+	pruneTab     pruneTab
+	pruneList    []pruneEntry // in the Brewfile, not installed
+	untracked    []pruneEntry // installed, not in the Brewfile
+	orphans      []pruneEntry // installed, neither in the Brewfile nor needed by it
 	pruneIdx     int
 	pruneLoading bool
+	ignorePath   string // ~/.mrk/sync-ignore
 
 	// Text input
 	inputBuf string
 
+	// Undo / redo
+	hist history
+
+	// Diff preview
+	diff       []string
+	diffTop    int
+	diffCommit bool // enter proceeds to the commit message
+
+	// System actions
+	dryRun         bool       // show brew commands instead of running them (--dry-run)
+	actQueue       [][]string // commands still to run in the current plan
+	actFailed      []string
+	actDesc        string
+	pendingAct     action   // awaiting confirmation
+	pendingTargets []*entry // what pendingAct applies to when not actionTargets, e.g. orphans
+
+	// External changes
+	watch    bool      // poll the Brewfile for changes (--watch)
+	external bool      // the watcher saw a change that conflicts with unsaved edits
+	saveThen afterSave // what the save behind the conflict prompt was for
+
 	// UI
 	width  int
 	height int
@@ -477,15 +465,32 @@ type model struct {
 }
 
 func newModel(bf *brewfile) model {
-	return model{bf: bf, leftFocus: true}
+	return model{
+		bf:         bf,
+		brew:       newExecBrew(),
+		leftFocus:  true,
+		ignorePath: syncIgnorePath(),
+		profile:    bfile.ActiveProfile(),
+
+		detailCachePath: defaultDetailCachePath(),
+	}
 }
 
 func (m model) Init() tea.Cmd {
-	sec := m.currentSection()
-	if sec == nil {
-		return nil
+	var cmds []tea.Cmd
+	if m.watch {
+		cmds = append(cmds, watchTick())
+	}
+	if sec := m.currentSection(); sec != nil {
+		cmds = append(cmds, fetchSectionDescs(sec.Entries))
+	}
+	if m.brew != nil {
+		cmds = append(cmds, fetchStatus(m.brew))
 	}
-	return fetchSectionDescs(sec.entries)
+	if m.detailCachePath != "" {
+		cmds = append(cmds, loadDetailCache(m.detailCachePath))
+	}
+	return tea.Batch(cmds...)
 }
 
 // ── Update ────────────────────────────────────────────────────────────────
@@ -504,9 +509,59 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		for k, v := range msg {
 			m.descCache[k] = v
 		}
-	case pruneMsg:
-		m.pruneList = []pruneEntry(msg)
+	case driftMsg:
 		m.pruneLoading = false
+		if msg.err != nil {
+			m.state = stateNormal
+			m.flash = "installed check failed: " + msg.err.Error()
+			break
+		}
+		m.pruneList, m.untracked, m.orphans = msg.missing, msg.untracked, msg.orphans
+	case detailCacheMsg:
+		m = m.seedDetails(msg)
+	case detailMsg:
+		return m.applyDetail(msg)
+	case gitLogMsg:
+		m.logLoading = false
+		if msg.err != nil {
+			m.state = stateNormal
+			m.flash = "history failed: " + msg.err.Error()
+			break
+		}
+		m.commits = msg.commits
+	case lintTapsMsg:
+		m.lintTaps = msg
+		m.lintLoading = false
+		m = m.relint()
+	case statusMsg:
+		if msg.err != nil {
+			m.flash = "installed check failed: " + msg.err.Error()
+			break
+		}
+		// Keep the cursor on the same entry as the filter re-applies.
+		cur, focus := m.currentEntry(), m.leftFocus
+		m.status = msg.state
+		if cur != nil {
+			m = m.jumpTo(cur)
+			m.leftFocus = focus
+		}
+		m.clampCursor()
+	case watchMsg:
+		return m.checkExternal(), watchTick()
+	case addSearchTickMsg:
+		if m.state == stateAddName && msg.seq == m.addSeq {
+			return m, searchBrew(m.brew, msg.seq, strings.TrimSpace(m.inputBuf))
+		}
+	case addSearchMsg:
+		if m.state == stateAddName && msg.seq == m.addSeq {
+			return m.applyAddSearch(msg)
+		}
+	case actionDoneMsg:
+		return m.actionDone(msg)
+	case lookupMsg:
+		if m.state == stateAddLookup && msg.name == m.addName {
+			return m.applyLookup(msg), nil
+		}
 	}
 	return m, nil
 }
@@ -525,15 +580,39 @@ func (m model) handleKey(msg tea.KeyMsg) (model, tea.Cmd) {
 	case stateSearch:
 		return m.handleSearch(key, msg)
 	case stateAddName:
+		return m.handleAddName(key, msg)
+	case stateAddLookup:
+		if key == "esc" {
+			m.state = stateNormal
+			m.flash = "cancelled"
+		}
+		return m, nil
+	case stateAddKind:
+		return m.handleAddKind(key)
+	case stateAddMasID:
 		return m.handleInputState(key, msg, func(m model) model {
-			m.addName = strings.TrimSpace(m.inputBuf)
+			id := strings.TrimSpace(m.inputBuf)
+			if strings.Trim(id, "0123456789") != "" {
+				m.flash = "mas id must be numeric"
+				return m
+			}
+			m.addOpts = []bfile.Option{{Key: "id", Value: id}}
 			m.inputBuf = ""
-			m.state = stateAddKind
-			m.addKindIdx = 0
+			m.addSecIdx = m.secIdx
+			m.state = stateAddSection
 			return m
 		})
-	case stateAddKind:
-		return m.handleAddKind(key)
+	case stateOptions:
+		return m.handleOptions(key)
+	case stateOptionInput:
+		if key == "esc" {
+			m.inputBuf = ""
+			m.state = stateOptions
+			return m, nil
+		}
+		return m.handleInputState(key, msg, func(m model) model {
+			return m.applyOptionInput()
+		})
 	case stateAddSection:
 		return m.handleAddSection(key)
 	case stateMove:
@@ -542,6 +621,58 @@ func (m model) handleKey(msg tea.KeyMsg) (model, tea.Cmd) {
 		return m.handleDeleteConfirm(key)
 	case statePrune:
 		return m.handlePrune(key)
+	case stateAdopt:
+		return m.handleAdopt(key)
+	case stateQuitConfirm:
+		return m.handleQuitConfirm(key)
+	case stateDiff:
+		return m.handleDiff(key)
+	case stateConflict:
+		return m.handleConflict(key)
+	case stateLint:
+		return m.handleLint(key)
+	case stateGitLog:
+		return m.handleGitLog(key)
+	case stateGitChanges:
+		return m.handleGitChanges(key)
+	case stateDetail:
+		return m.handleDetail(key)
+	case stateNotes:
+		return m.handleNotes(key)
+	case stateNoteInput:
+		if key == "esc" {
+			m.inputBuf = ""
+			m.state = stateNotes
+			return m, nil
+		}
+		if key == "enter" {
+			// An empty inline comment is a valid edit: it removes it.
+			return m.applyNoteInput(), nil
+		}
+		return m.handleInputState(key, msg, nil)
+	case stateProfileInput:
+		if key == "enter" {
+			// An empty prompt is a valid edit: it removes the tag.
+			return m.applyProfileInput(), nil
+		}
+		return m.handleInputState(key, msg, nil)
+	case stateSectionName:
+		return m.handleInputState(key, msg, func(m model) model {
+			return m.applySectionName()
+		})
+	case stateSectionMerge:
+		return m.handleSectionMerge(key)
+	case stateSectionDeleteConfirm:
+		return m.handleSectionDeleteConfirm(key)
+	case stateActionConfirm:
+		targets, back := m.confirmTargets()
+		m.pendingTargets = nil
+		if key == "y" || key == "enter" {
+			return m.startAction(m.pendingAct, targets)
+		}
+		m.state = back
+		m.flash = "cancelled"
+		return m, nil
 	case stateCommit:
 		return m.handleInputState(key, msg, func(m model) model {
 			msg := strings.TrimSpace(m.inputBuf)
@@ -566,8 +697,15 @@ func (m model) handleKey(msg tea.KeyMsg) (model, tea.Cmd) {
 func (m model) handleNormal(key string) (model, tea.Cmd) {
 	m.flash = ""
 	prevSec := m.secIdx
+	if mm, ok := m.handleMarkKey(key); ok {
+		return mm, nil
+	}
 	switch key {
 	case "q", "esc":
+		if m.dirty {
+			m.state = stateQuitConfirm
+			return m, nil
+		}
 		return m, tea.Quit
 
 	// Pane navigation
@@ -579,6 +717,14 @@ func (m model) handleNormal(key string) (model, tea.Cmd) {
 		if m.currentSection() != nil {
 			m.leftFocus = false
 		}
+	case "enter":
+		if m.leftFocus {
+			if m.currentSection() != nil {
+				m.leftFocus = false
+			}
+		} else if m.currentEntry() != nil {
+			return m.openDetail(false)
+		}
 
 	// Section navigation (left pane)
 	case "up", "k":
@@ -599,8 +745,7 @@ func (m model) handleNormal(key string) (model, tea.Cmd) {
 				m.entIdx = 0
 			}
 		} else {
-			sec := m.currentSection()
-			if sec != nil && m.entIdx < len(sec.entries)-1 {
+			if m.entIdx < len(m.entries(m.currentSection()))-1 {
 				m.entIdx++
 			}
 		}
@@ -608,34 +753,116 @@ func (m model) handleNormal(key string) (model, tea.Cmd) {
 	// Actions
 	case "a":
 		m.inputBuf = ""
+		m.addResults = nil
+		m.addResIdx = -1
 		m.state = stateAddName
 	case "d":
-		if m.currentEntry() != nil {
+		if m.leftFocus {
+			m = m.removeCurrentSection()
+		} else if m.currentEntry() != nil || m.hasMarks() {
 			m.state = stateDeleteConfirm
 		}
+	case "n":
+		m.inputBuf = ""
+		m.secRename = false
+		m.state = stateSectionName
+	case "r":
+		if sec := m.currentSection(); sec != nil && sec.Line >= 0 {
+			m.inputBuf = sec.Name
+			m.secRename = true
+			m.state = stateSectionName
+		}
+	case "K", "shift+up":
+		m = m.moveCurrentSection(-1)
+	case "J", "shift+down":
+		m = m.moveCurrentSection(1)
 	case "m":
-		if m.currentEntry() != nil {
+		if m.currentEntry() != nil || m.hasMarks() {
 			m.moveSecIdx = m.secIdx
 			m.state = stateMove
 		}
+	case "o":
+		if m.currentEntry() != nil {
+			m.optIdx = 0
+			m.state = stateOptions
+		}
+	case "H":
+		m.commits = nil
+		m.logIdx = 0
+		m.logLoading = true
+		m.state = stateGitLog
+		return m, fetchGitLog(m.bf)
+	case "L":
+		m.lintIdx = 0
+		m.lintLoading = true
+		m.state = stateLint
+		return m, fetchLintTaps(m.brew, m.bf)
+	case "#":
+		if m.currentEntry() != nil {
+			m.noteIdx = 0
+			m.state = stateNotes
+		}
 	case "g":
-		if e := m.currentEntry(); e != nil {
-			if e.kind != kindCask {
+		if m.hasMarks() {
+			m = m.greedyMarked()
+		} else if e := m.currentEntry(); e != nil {
+			if e.Kind != kindCask {
 				m.flash = "greedy only applies to casks"
 			} else {
+				m.checkpoint(fmt.Sprintf("toggle greedy on \"%s\"", e.Name))
 				m.bf.toggleGreedy(e)
-				m.bf.reload()
 				m.dirty = true
 				m.flash = "toggled greedy"
 				m.clampCursor()
 			}
 		}
+	case "i":
+		return m.startAction(actInstall, m.actionTargets())
+	case "U":
+		return m.startAction(actUpgrade, m.actionTargets())
+	case "x":
+		if len(m.actionTargets()) > 0 {
+			m.pendingAct = actUninstall
+			m.state = stateActionConfirm
+		}
+	case "f":
+		if m.status == nil {
+			m.flash = "installed state not loaded yet"
+			break
+		}
+		m.filterAttention = !m.filterAttention
+		m.entIdx = 0
+		if m.filterAttention {
+			m.flash = "showing missing and outdated entries only"
+		} else {
+			m.flash = "showing all entries"
+		}
+	case "P":
+		cur, focus := m.currentEntry(), m.leftFocus
+		m.allProfiles = !m.allProfiles
+		if cur != nil && m.inProfile(cur) {
+			m = m.jumpTo(cur)
+			m.leftFocus = focus
+		}
+		m.clampCursor()
+		if m.allProfiles {
+			m.flash = "showing every profile"
+		} else {
+			m.flash = "showing profile " + m.profile
+		}
+	case "@":
+		m = m.startProfileInput()
+	case "u":
+		m = m.undo()
+	case "ctrl+r":
+		m = m.redo()
 	case "p":
-		m.pruneList = nil
+		m.pruneList, m.untracked, m.orphans = nil, nil, nil
 		m.pruneIdx = 0
+		m.pruneTab = tabMissing
 		m.pruneLoading = true
 		m.state = statePrune
-		return m, fetchPruneList(m.bf)
+		return m, fetchDrift(m.brew, m.bf, m.profile, m.ignorePath)
 	case "/":
 		m.searchQuery = ""
 		m.inputBuf = ""
@@ -643,29 +870,37 @@ func (m model) handleNormal(key string) (model, tea.Cmd) {
 		m.searchIdx = 0
 		m.state = stateSearch
 	case "w":
-		if err := m.bf.save(); err != nil {
-			m.flash = "save failed: " + err.Error()
-		} else {
-			m.flash = "saved"
-			m.dirty = false
+		return m.trySave(afterSaveNone)
+	case "v":
+		m.diff = unifiedDiff("a/Brewfile (on disk)", "b/Brewfile (unsaved)", m.bf.saved, m.bf.lines, 3)
+		if m.diff == nil {
+			m.flash = "no unsaved changes"
+			break
 		}
+		m.diffTop = 0
+		m.diffCommit = false
+		m.state = stateDiff
 	case "c":
 		if !m.dirty {
 			m.flash = "no unsaved changes to commit"
-		} else {
-			if err := m.bf.save(); err != nil {
-				m.flash = "save failed: " + err.Error()
-				break
-			}
-			m.inputBuf = "Brewfile: "
-			m.state = stateCommit
+			break
 		}
+		// Preview against HEAD, which is what the commit will record;
+		// fall back to the on-disk copy outside a git checkout.
+		base, baseName := m.bf.saved, "a/Brewfile (on disk)"
+		if head, ok := m.bf.headLines(); ok {
+			base, baseName = head, "a/Brewfile (HEAD)"
+		}
+		m.diff = unifiedDiff(baseName, "b/Brewfile (to commit)", base, m.bf.lines, 3)
+		m.diffTop = 0
+		m.diffCommit = true
+		m.state = stateDiff
 	}
 
 	var cmd tea.Cmd
 	if m.secIdx != prevSec {
 		if sec := m.currentSection(); sec != nil {
-			if missing := m.missingDescs(sec.entries); len(missing) > 0 {
+			if missing := m.missingDescs(sec.Entries); len(missing) > 0 {
 				cmd = fetchSectionDescs(missing)
 			}
 		}
@@ -682,9 +917,7 @@ func (m model) handleSearch(key string, msg tea.KeyMsg) (model, tea.Cmd) {
 	case "enter":
 		if len(m.searchResults) > 0 && m.searchIdx < len(m.searchResults) {
 			r := m.searchResults[m.searchIdx]
-			m.secIdx = r.secIdx
-			m.entIdx = r.entIdx
-			m.leftFocus = false
+			m = m.jumpTo(m.bf.sections[r.secIdx].Entries[r.entIdx])
 		}
 		m.state = stateNormal
 		m.searchQuery = ""
@@ -702,14 +935,14 @@ func (m model) handleSearch(key string, msg tea.KeyMsg) (model, tea.Cmd) {
 			_, size := utf8.DecodeLastRuneInString(m.inputBuf)
 			m.inputBuf = m.inputBuf[:len(m.inputBuf)-size]
 			m.searchQuery = m.inputBuf
-			m.searchResults = m.bf.search(m.searchQuery)
+			m.searchResults = m.bf.search(m.searchQuery, m.descCache)
 			m.searchIdx = 0
 		}
 	default:
 		if len(msg.Runes) > 0 {
 			m.inputBuf += string(msg.Runes)
 			m.searchQuery = m.inputBuf
-			m.searchResults = m.bf.search(m.searchQuery)
+			m.searchResults = m.bf.search(m.searchQuery, m.descCache)
 			m.searchIdx = 0
 		}
 	}
@@ -721,14 +954,21 @@ func (m model) handleAddKind(key string) (model, tea.Cmd) {
 	case "esc":
 		m.state = stateNormal
 	case "up", "k", "left", "h":
-		m.addKindIdx = 0
+		if m.addKindIdx > 0 {
+			m.addKindIdx--
+		}
 	case "down", "j", "right", "l":
-		m.addKindIdx = 1
+		if m.addKindIdx < len(addKinds)-1 {
+			m.addKindIdx++
+		}
 	case "enter", " ":
-		if m.addKindIdx == 0 {
-			m.addKind = kindBrew
-		} else {
-			m.addKind = kindCask
+		m.addKind = addKinds[m.addKindIdx]
+		m.addOpts = nil
+		if m.addKind == kindMas {
+			// mas entries are keyed by App Store id, not name.
+			m.inputBuf = ""
+			m.state = stateAddMasID
+			return m, nil
 		}
 		m.addSecIdx = m.secIdx
 		m.state = stateAddSection
@@ -751,31 +991,20 @@ func (m model) handleAddSection(key string) (model, tea.Cmd) {
 		}
 	case "enter":
 		if m.addSecIdx < len(secs) {
-			for _, sec := range m.bf.sections {
-				for _, e := range sec.entries {
-					if e.name == m.addName && e.kind == m.addKind {
-						m.flash = "already in Brewfile"
-						m.state = stateNormal
-						return m, nil
-					}
-				}
+			if e := m.bf.doc.Find(m.addKind, m.addName); e != nil {
+				m = m.jumpTo(e)
+				m.flash = fmt.Sprintf("\"%s\" is already in %s", e.Name, m.bf.doc.SectionOf(e).Name)
+				m.state = stateNormal
+				return m, nil
 			}
-			secName := secs[m.addSecIdx].name
-			m.bf.addEntry(m.addName, m.addKind, false, secName)
+			secName := secs[m.addSecIdx].Name
+			m.checkpoint(fmt.Sprintf("add %s \"%s\"", m.addKind, m.addName))
+			m.bf.addEntry(m.addName, m.addKind, false, secName, m.addOpts...)
 			m.dirty = true
 			m.flash = fmt.Sprintf("added %s \"%s\"", m.addKind, m.addName)
 			// Navigate to new entry
-			m.secIdx = m.addSecIdx
-			m.leftFocus = false
-			m.clampCursor()
-			// Find the new entry
-			if sec := m.currentSection(); sec != nil {
-				for i, e := range sec.entries {
-					if e.name == m.addName {
-						m.entIdx = i
-						break
-					}
-				}
+			if e := m.bf.doc.Find(m.addKind, m.addName); e != nil {
+				m = m.jumpTo(e)
 			}
 		}
 		m.state = stateNormal
@@ -797,33 +1026,28 @@ func (m model) handleMove(key string) (model, tea.Cmd) {
 			m.moveSecIdx++
 		}
 	case "enter":
+		if m.hasMarks() && m.moveSecIdx < len(secs) {
+			m = m.moveMarked(secs[m.moveSecIdx].Name)
+			m.state = stateNormal
+			break
+		}
 		e := m.currentEntry()
 		if e != nil && m.moveSecIdx < len(secs) {
-			targetName := secs[m.moveSecIdx].name
-			if targetName == m.currentSection().name {
+			targetName := secs[m.moveSecIdx].Name
+			if targetName == m.currentSection().Name {
 				m.flash = "already in that section"
 				m.state = stateNormal
 				break
 			}
-			entName := e.name
+			entName, entKind := e.Name, e.Kind
+			m.checkpoint(fmt.Sprintf("move \"%s\" → %s", entName, targetName))
 			m.bf.moveEntry(e, targetName)
 			m.dirty = true
 			m.flash = fmt.Sprintf("moved \"%s\" → %s", entName, targetName)
 			// Navigate to moved entry
-			for si, s := range m.bf.sections {
-				if s.name == targetName {
-					m.secIdx = si
-					m.entIdx = 0
-					for ei, en := range s.entries {
-						if en.name == entName {
-							m.entIdx = ei
-							break
-						}
-					}
-					break
-				}
+			if moved := m.bf.doc.Find(entKind, entName); moved != nil {
+				m = m.jumpTo(moved)
 			}
-			m.leftFocus = false
 		}
 		m.state = stateNormal
 	}
@@ -833,8 +1057,11 @@ func (m model) handleMove(key string) (model, tea.Cmd) {
 func (m model) handleDeleteConfirm(key string) (model, tea.Cmd) {
 	switch key {
 	case "y", "d", "enter":
-		if e := m.currentEntry(); e != nil {
-			name := e.name
+		if m.hasMarks() {
+			m = m.deleteMarked()
+		} else if e := m.currentEntry(); e != nil {
+			name := e.Name
+			m.checkpoint(fmt.Sprintf("remove \"%s\"", name))
 			m.bf.deleteEntry(e)
 			m.dirty = true
 			m.flash = fmt.Sprintf("removed \"%s\"", name)
@@ -848,62 +1075,142 @@ func (m model) handleDeleteConfirm(key string) (model, tea.Cmd) {
 	return m, nil
 }
 
-func (m model) handlePrune(key string) (model, tea.Cmd) {
+func (m model) handleOptions(key string) (model, tea.Cmd) {
+	e := m.currentEntry()
+	if e == nil {
+		m.state = stateNormal
+		return m, nil
+	}
+	m.flash = ""
 	switch key {
 	case "esc", "q":
 		m.state = stateNormal
 	case "up", "k":
-		if m.pruneIdx > 0 {
-			m.pruneIdx--
+		if m.optIdx > 0 {
+			m.optIdx--
 		}
 	case "down", "j":
-		if m.pruneIdx < len(m.pruneList)-1 {
-			m.pruneIdx++
-		}
-	case " ":
-		if m.pruneIdx < len(m.pruneList) {
-			m.pruneList[m.pruneIdx].marked = !m.pruneList[m.pruneIdx].marked
-			if m.pruneIdx < len(m.pruneList)-1 {
-				m.pruneIdx++
-			}
+		if m.optIdx < len(e.Options)-1 {
+			m.optIdx++
 		}
 	case "a":
-		// Toggle all
-		allMarked := true
-		for _, p := range m.pruneList {
-			if !p.marked {
-				allMarked = false
-				break
+		m.inputBuf = ""
+		m.optEditIdx = -1
+		m.state = stateOptionInput
+	case "enter", "e":
+		if m.optIdx < len(e.Options) {
+			m.inputBuf = e.Options[m.optIdx].String()
+			m.optEditIdx = m.optIdx
+			m.state = stateOptionInput
+		}
+	case "d", "x":
+		if m.optIdx < len(e.Options) {
+			optKey := e.Options[m.optIdx].Key
+			opts := append(append([]bfile.Option(nil), e.Options[:m.optIdx]...), e.Options[m.optIdx+1:]...)
+			m.checkpoint(fmt.Sprintf("remove option %s from \"%s\"", optKey, e.Name))
+			m.bf.setOptions(e, opts)
+			m.dirty = true
+			m.flash = "removed option " + optKey
+			if m.optIdx > 0 && m.optIdx >= len(opts) {
+				m.optIdx--
 			}
 		}
-		for i := range m.pruneList {
-			m.pruneList[i].marked = !allMarked
-		}
-	case "enter", "d":
-		marked := 0
-		for _, p := range m.pruneList {
-			if p.marked {
-				marked++
-			}
+	}
+	return m, nil
+}
+
+func (m model) handleQuitConfirm(key string) (model, tea.Cmd) {
+	switch key {
+	case "y":
+		return m, tea.Quit
+	case "w":
+		return m.trySave(afterSaveQuit)
+	case "v":
+		m.diff = unifiedDiff("a/Brewfile (on disk)", "b/Brewfile (unsaved)", m.bf.saved, m.bf.lines, 3)
+		m.diffTop = 0
+		m.diffCommit = false
+		m.state = stateDiff
+	default:
+		m.state = stateNormal
+		m.flash = "cancelled"
+	}
+	return m, nil
+}
+
+// diffPageSize is how many diff lines fit in the body below its title.
+func (m model) diffPageSize() int {
+	bodyH := max(m.height-2, 4)
+	return max(1, bodyH-2-1)
+}
+
+func (m model) handleDiff(key string) (model, tea.Cmd) {
+	page := m.diffPageSize()
+	last := max(0, len(m.diff)-page)
+	switch key {
+	case "esc", "q":
+		if m.diffCommit {
+			m.flash = "commit cancelled"
 		}
-		if marked == 0 {
-			m.flash = "nothing selected"
-			m.state = stateNormal
-			return m, nil
+		m.state = stateNormal
+	case "up", "k":
+		m.diffTop--
+	case "down", "j":
+		m.diffTop++
+	case "pgup", "ctrl+u", "b":
+		m.diffTop -= page
+	case "pgdown", "ctrl+d", " ", "f":
+		m.diffTop += page
+	case "home", "g":
+		m.diffTop = 0
+	case "end", "G":
+		m.diffTop = last
+	case "enter", "c":
+		if !m.diffCommit {
+			break
 		}
-		// Delete all marked entries (iterate in reverse to preserve indices)
-		for i := len(m.pruneList) - 1; i >= 0; i-- {
-			if m.pruneList[i].marked {
-				m.bf.deleteEntry(m.pruneList[i].entryRef)
+		return m.trySave(afterSaveCommit)
+	}
+	m.diffTop = min(max(m.diffTop, 0), last)
+	return m, nil
+}
+
+// applyOptionInput parses inputBuf as "key: value" and stores it on the
+// current entry, replacing the option being edited or one with the same key.
+func (m model) applyOptionInput() model {
+	e := m.currentEntry()
+	if e == nil {
+		m.state = stateNormal
+		return m
+	}
+	opt, ok := bfile.ParseOption(m.inputBuf)
+	if !ok {
+		m.flash = "invalid option — expected key: value"
+		return m
+	}
+	opts := append([]bfile.Option(nil), e.Options...)
+	idx := m.optEditIdx
+	if idx < 0 {
+		for i, o := range opts {
+			if o.Key == opt.Key {
+				idx = i
+				break
 			}
 		}
-		m.dirty = true
-		m.flash = fmt.Sprintf("removed %d uninstalled entry/entries", marked)
-		m.pruneList = nil
-		m.clampCursor()
-		m.state = stateNormal
 	}
-	return m, nil
+	if idx >= 0 && idx < len(opts) {
+		opts[idx] = opt
+	} else {
+		opts = append(opts, opt)
+		idx = len(opts) - 1
+	}
+	m.checkpoint(fmt.Sprintf("set %s on \"%s\"", opt, e.Name))
+	m.bf.setOptions(e, opts)
+	m.dirty = true
+	m.flash = "set " + opt.String()
+	m.optIdx = idx
+	m.inputBuf = ""
+	m.state = stateOptions
+	return m
 }
 
 // handleInputState processes a text input field and calls done when Enter is pressed.
@@ -939,12 +1246,9 @@ func (m model) currentSection() *section {
 }
 
 func (m model) currentEntry() *entry {
-	sec := m.currentSection()
-	if sec == nil {
-		return nil
-	}
-	if m.entIdx < len(sec.entries) {
-		return sec.entries[m.entIdx]
+	ents := m.entries(m.currentSection())
+	if m.entIdx < len(ents) {
+		return ents[m.entIdx]
 	}
 	return nil
 }
@@ -953,9 +1257,8 @@ func (m *model) clampCursor() {
 	if m.secIdx >= len(m.bf.sections) {
 		m.secIdx = max(0, len(m.bf.sections)-1)
 	}
-	sec := m.currentSection()
-	if sec != nil && m.entIdx >= len(sec.entries) {
-		m.entIdx = max(0, len(sec.entries)-1)
+	if n := len(m.entries(m.currentSection())); m.entIdx >= n {
+		m.entIdx = max(0, n-1)
 	}
 }
 
@@ -980,15 +1283,22 @@ var (
 	styleEntCursor = lipgloss.NewStyle().Bold(true).Foreground(theme.ColHighlight)
 	styleEntNorm   = lipgloss.NewStyle().Foreground(theme.ColNormal)
 	styleGreedy    = lipgloss.NewStyle().Foreground(theme.ColAccent)
+	styleOpts      = lipgloss.NewStyle().Foreground(theme.ColAccent)
 	styleDim       = lipgloss.NewStyle().Foreground(theme.ColDim)
 	styleInput     = lipgloss.NewStyle().Foreground(theme.ColNormal)
 	styleInputPfx  = lipgloss.NewStyle().Foreground(theme.ColAccent).Bold(true)
 	stylePrompt    = lipgloss.NewStyle().Foreground(theme.ColAmber).Bold(true)
 	styleSearchHit = lipgloss.NewStyle().Foreground(theme.ColHighlight).Bold(true)
 	styleSearchSec = lipgloss.NewStyle().Foreground(theme.ColSubtle)
+	styleMatch     = lipgloss.NewStyle().Foreground(theme.ColAmber).Bold(true)
 	styleKindSel   = lipgloss.NewStyle().Bold(true).Foreground(theme.ColHighlight)
 	styleKindNorm  = lipgloss.NewStyle().Foreground(theme.ColNormal)
 	styleDelete    = lipgloss.NewStyle().Foreground(theme.ColRed).Bold(true)
+	styleDiffAdd   = lipgloss.NewStyle().Foreground(theme.ColGreen)
+	styleInstalled = lipgloss.NewStyle().Foreground(theme.ColGreen)
+	styleMissing   = lipgloss.NewStyle().Foreground(theme.ColRed)
+	styleOutdated  = lipgloss.NewStyle().Foreground(theme.ColAmber)
+	styleNote      = lipgloss.NewStyle().Foreground(theme.ColSubtle).Italic(true)
 )
 
 // ── View ──────────────────────────────────────────────────────────────────
@@ -1008,8 +1318,17 @@ func (m model) View() string {
 func (m model) viewHeader() string {
 	left := theme.StyleTitle.Render("bf") + theme.StyleFooter.Render("  Brewfile Manager")
 	dirtyMark := ""
+	if m.dryRun {
+		dirtyMark += styleDim.Render(" [dry run]")
+	}
 	if m.dirty {
-		dirtyMark = styleDirty.Render(" ●")
+		dirtyMark += styleDirty.Render(" ●")
+	}
+	if m.external {
+		dirtyMark += styleDirty.Render(" changed on disk")
+	}
+	if label := m.profileLabel(); label != "" {
+		left += styleOpts.Render("  " + label)
 	}
 	path := theme.StyleFooter.Render(m.bf.path) + dirtyMark
 	gap := m.width - lipgloss.Width(left) - lipgloss.Width(path)
@@ -1022,26 +1341,104 @@ func (m model) viewHeader() string {
 func (m model) viewFooter() string {
 	switch m.state {
 	case stateAddName:
-		return styleInputPfx.Render(" add › name: ") + styleInput.Render(m.inputBuf+"█")
+		return styleInputPfx.Render(" add › name: ") + styleInput.Render(m.inputBuf+"█") + m.flashSuffix()
+	case stateAddLookup:
+		return styleInputPfx.Render(" add › ") + styleDim.Render(fmt.Sprintf("looking up \"%s\" in Homebrew…", m.addName)) +
+			theme.StyleFooter.Render("  esc cancel")
 	case stateAddKind:
-		brew := styleKindNorm.Render("  brew  ")
-		cask := styleKindNorm.Render("  cask  ")
-		if m.addKindIdx == 0 {
-			brew = styleKindSel.Render("▸ brew  ")
-		} else {
-			cask = styleKindSel.Render("▸ cask  ")
+		var kinds strings.Builder
+		for i, k := range addKinds {
+			if i == m.addKindIdx {
+				kinds.WriteString(styleKindSel.Render("▸ " + k.String() + "  "))
+			} else {
+				kinds.WriteString(styleKindNorm.Render("  " + k.String() + "  "))
+			}
 		}
-		return styleInputPfx.Render(" add › type: ") + brew + cask + theme.StyleFooter.Render("  ↑↓ choose · enter confirm · esc cancel")
+		return styleInputPfx.Render(" add › type: ") + kinds.String() + theme.StyleFooter.Render("  ←→ choose · enter confirm · esc cancel") + m.flashSuffix()
+	case stateAddMasID:
+		return styleInputPfx.Render(" add › app store id: ") + styleInput.Render(m.inputBuf+"█") + m.flashSuffix()
+	case stateOptions:
+		return theme.StyleFooter.Render("[a]dd  [enter/e]dit  [d]elete  [esc] back") + m.flashSuffix()
+	case stateLint:
+		return theme.StyleFooter.Render("↑↓ navigate  [enter] go to line  [s]ort all sections  [esc] back") + m.flashSuffix()
+	case stateGitLog:
+		return theme.StyleFooter.Render("↑↓ navigate  [enter] show package changes  [esc] back") + m.flashSuffix()
+	case stateGitChanges:
+		return theme.StyleFooter.Render("↑↓ navigate  [r]evert this change  [u]ndo  [enter] go to entry  [esc] commits") + m.flashSuffix()
+	case stateDetail:
+		return theme.StyleFooter.Render("↑↓ previous/next entry  [r]efresh from brew  [esc] back") + m.flashSuffix()
+	case stateNotes:
+		return theme.StyleFooter.Render("[a]dd line above  [enter/e]dit  [d]elete  [esc] back") + m.flashSuffix()
+	case stateNoteInput:
+		return styleInputPfx.Render(" comment › # ") + styleInput.Render(m.inputBuf+"█") +
+			theme.StyleFooter.Render("  enter save · esc cancel") + m.flashSuffix()
+	case stateProfileInput:
+		return styleInputPfx.Render(" profile › ") + styleInput.Render(m.inputBuf+"█") +
+			theme.StyleFooter.Render("  names, space-separated · empty for every profile · enter save · esc cancel") + m.flashSuffix()
+	case stateOptionInput:
+		return styleInputPfx.Render(" option › ") + styleInput.Render(m.inputBuf+"█") +
+			theme.StyleFooter.Render("  key: value · enter save · esc cancel") + m.flashSuffix()
 	case stateDeleteConfirm:
+		if marked := m.markedEntries(); len(marked) > 0 {
+			return styleDelete.Render(fmt.Sprintf(" delete %d entries (%s)? ", len(marked), markedNames(marked))) +
+				stylePrompt.Render("[y]") + theme.StyleFooter.Render("es  ") +
+				stylePrompt.Render("[n]") + theme.StyleFooter.Render("o")
+		}
 		e := m.currentEntry()
 		if e == nil {
 			return ""
 		}
-		return styleDelete.Render(fmt.Sprintf(" delete \"%s\"? ", e.name)) +
+		return styleDelete.Render(fmt.Sprintf(" delete \"%s\"? ", e.Name)) +
 			stylePrompt.Render("[y]") + theme.StyleFooter.Render("es  ") +
 			stylePrompt.Render("[n]") + theme.StyleFooter.Render("o")
 	case stateCommit:
 		return styleInputPfx.Render(" commit: ") + styleInput.Render(m.inputBuf+"█")
+	case stateQuitConfirm:
+		return styleDelete.Render(" unsaved changes — quit? ") +
+			stylePrompt.Render("[w]") + theme.StyleFooter.Render("rite & quit  ") +
+			stylePrompt.Render("[y]") + theme.StyleFooter.Render(" discard  ") +
+			stylePrompt.Render("[v]") + theme.StyleFooter.Render("iew diff  ") +
+			stylePrompt.Render("[n]") + theme.StyleFooter.Render("o")
+	case stateSectionName:
+		label := " new section › header: "
+		if m.secRename {
+			label = " rename section › "
+		}
+		return styleInputPfx.Render(label) + styleInput.Render(m.inputBuf+"█") +
+			theme.StyleFooter.Render("  enter save · esc cancel")
+	case stateSectionMerge:
+		return theme.StyleFooter.Render("[enter] merge entries into section  [D] delete section and its entries  [esc] cancel") + m.flashSuffix()
+	case stateSectionDeleteConfirm:
+		sec := m.currentSection()
+		if sec == nil {
+			return ""
+		}
+		return styleDelete.Render(fmt.Sprintf(" delete empty section \"%s\"? ", sec.Name)) +
+			stylePrompt.Render("[y]") + theme.StyleFooter.Render("es  ") +
+			stylePrompt.Render("[n]") + theme.StyleFooter.Render("o")
+	case stateActionConfirm:
+		targets, _ := m.confirmTargets()
+		if len(targets) == 0 {
+			return ""
+		}
+		what := fmt.Sprintf("\"%s\"", targets[0].Name)
+		if len(targets) > 1 {
+			what = fmt.Sprintf("%d entries", len(targets))
+		}
+		return styleDelete.Render(fmt.Sprintf(" %s %s from this Mac? ", m.pendingAct, what)) +
+			stylePrompt.Render("[y]") + theme.StyleFooter.Render("es  ") +
+			stylePrompt.Render("[n]") + theme.StyleFooter.Render("o")
+	case stateConflict:
+		return styleDelete.Render(" Brewfile changed on disk — ") +
+			stylePrompt.Render("[r]") + theme.StyleFooter.Render("eload theirs  ") +
+			stylePrompt.Render("[m]") + theme.StyleFooter.Render("erge  ") +
+			stylePrompt.Render("[o]") + theme.StyleFooter.Render("verwrite  ") +
+			stylePrompt.Render("[esc]") + theme.StyleFooter.Render(" cancel") + m.flashSuffix()
+	case stateDiff:
+		if m.diffCommit {
+			return theme.StyleFooter.Render("↑↓/pgup/pgdn scroll  [enter] save & write message  [esc] cancel commit")
+		}
+		return theme.StyleFooter.Render("↑↓/pgup/pgdn scroll  [esc] back")
 	case stateSearch:
 		indicator := styleInputPfx.Render(" / ")
 		n := ""
@@ -1054,31 +1451,42 @@ func (m model) viewFooter() string {
 		if m.pruneLoading {
 			return theme.StyleFooter.Render("checking installed packages…")
 		}
-		marked := 0
-		for _, p := range m.pruneList {
-			if p.marked {
-				marked++
-			}
-		}
 		sel := ""
-		if marked > 0 {
+		if marked := len(markedPrune(*m.pruneItems())); marked > 0 {
 			sel = styleDelete.Render(fmt.Sprintf("  %d selected", marked))
 		}
-		return theme.StyleFooter.Render("[space] mark  [a] all  [enter/d] delete marked  [esc] cancel") + sel
+		switch m.pruneTab {
+		case tabUntracked:
+			return theme.StyleFooter.Render("[tab] next list  [space] mark  [a] all  [enter] add to a section  [I] ignore  [esc] cancel") + sel + m.flashSuffix()
+		case tabOrphans:
+			return theme.StyleFooter.Render("[tab] next list  [space] mark  [a] all  [enter/x] uninstall marked  [esc] cancel") + sel + m.flashSuffix()
+		}
+		return theme.StyleFooter.Render("[tab] next list  [space] mark  [a] all  [enter/d] delete marked  [i] install marked  [esc] cancel") + sel + m.flashSuffix()
 	default:
-		var flashStr string
-		if m.flash != "" {
-			if strings.Contains(m.flash, "fail") || strings.Contains(m.flash, "only") {
-				flashStr = "  " + styleFlashWarn.Render(m.flash)
-			} else {
-				flashStr = "  " + styleFlash.Render(m.flash)
-			}
+		hints := theme.StyleFooter.Render("[enter]info [a]dd [d]el [m]ove [g]reedy [o]pts [#]notes [@]profile [i]nst [U]pg [x]uninst [p]rune [L]int [H]istory [f]ilter [u]ndo [v]diff [/]search [w]rite [c]ommit [q]uit")
+		if m.leftFocus {
+			hints = theme.StyleFooter.Render("[n]ew [r]ename [J/K] move [d]elete/merge section · [a]dd [p]rune [f]ilter [P]rofiles [u]ndo [v]diff [/]search [w]rite [c]ommit [q]uit")
+		} else if m.hasMarks() {
+			hints = theme.StyleFooter.Render("[space] mark [V]isual [*] all · marked: [d]el [m]ove [g]reedy on/off [@]profile [i]nst [U]pg [x]uninst · [esc] clear")
 		}
-		hints := theme.StyleFooter.Render("[a]dd [d]el [m]ove [g]reedy [p]rune [/]search [w]rite [c]ommit [q]uit")
-		return hints + flashStr
+		return hints + m.markSummary() + m.flashSuffix()
 	}
 }
 
+// flashSuffix renders the flash message for the footer, amber for failures.
+func (m model) flashSuffix() string {
+	if m.flash == "" {
+		return ""
+	}
+	if strings.Contains(m.flash, "fail") || strings.Contains(m.flash, "only") ||
+		strings.Contains(m.flash, "invalid") || strings.Contains(m.flash, "must") ||
+		strings.Contains(m.flash, "already") || strings.Contains(m.flash, "not found") ||
+		strings.Contains(m.flash, "exited") || strings.Contains(m.flash, "not available") {
+		return "  " + styleFlashWarn.Render(m.flash)
+	}
+	return "  " + styleFlash.Render(m.flash)
+}
+
 func (m model) viewBody() string {
 	// Height: total - header(1) - footer(1)
 	bodyH := m.height - 2
@@ -1089,12 +1497,35 @@ func (m model) viewBody() string {
 	switch m.state {
 	case stateSearch:
 		return m.viewSearch(bodyH)
+	case stateAddName:
+		return m.viewAddSearch(bodyH)
 	case stateAddSection:
 		return m.viewSectionPicker("add › section:", m.addSecIdx, bodyH)
 	case stateMove:
-		return m.viewSectionPicker("move › section:", m.moveSecIdx, bodyH)
+		title := "move › section:"
+		if n := len(m.markedEntries()); n > 0 {
+			title = fmt.Sprintf("move %d entries › section:", n)
+		}
+		return m.viewSectionPicker(title, m.moveSecIdx, bodyH)
+	case stateSectionMerge:
+		return m.viewSectionPicker(fmt.Sprintf("merge %s › into:", m.currentSection().Name), m.moveSecIdx, bodyH)
 	case statePrune:
 		return m.viewPrune(bodyH)
+	case stateAdopt:
+		n := max(1, len(markedPrune(m.untracked)))
+		return m.viewSectionPicker(fmt.Sprintf("add %d package(s) › section:", n), m.moveSecIdx, bodyH)
+	case stateOptions, stateOptionInput:
+		return m.viewOptions(bodyH)
+	case stateNotes, stateNoteInput:
+		return m.viewNotes(bodyH)
+	case stateLint:
+		return m.viewLint(bodyH)
+	case stateGitLog, stateGitChanges:
+		return m.viewGitLog(bodyH)
+	case stateDetail:
+		return m.viewDetail(bodyH)
+	case stateDiff:
+		return m.viewDiff(bodyH)
 	default:
 		return m.viewTwoPanes(bodyH)
 	}
@@ -1129,12 +1560,12 @@ func (m model) viewLeft(inner, height int) string {
 		if i < start || lines >= height {
 			continue
 		}
-		badge := styleBadge.Render(fmt.Sprintf("(%d)", len(sec.entries)))
+		badge := styleBadge.Render(fmt.Sprintf("(%d)", len(m.entries(sec))))
 		nameW := inner - lipgloss.Width(badge) - 3
 		if nameW < 1 {
 			nameW = 1
 		}
-		name := theme.Truncate(sec.name, nameW)
+		name := theme.Truncate(sec.Name, nameW)
 		pad := strings.Repeat(" ", max(0, nameW-lipgloss.Width(name)))
 
 		var line string
@@ -1167,14 +1598,36 @@ func (m model) viewRight(inner, height int) string {
 	}
 
 	sec := m.currentSection()
-	if sec == nil || len(sec.entries) == 0 {
+	ents := m.entries(sec)
+	if sec == nil || len(sec.Entries) == 0 {
 		return pane.Width(inner).Height(height).Render(styleDim.Render("empty section"))
 	}
+	if len(ents) == 0 {
+		empty := styleFlash.Render("✓ nothing missing or outdated here")
+		if !m.filterAttention {
+			empty = styleDim.Render("nothing for profile " + m.profile + " here — P shows every profile")
+		}
+		return pane.Width(inner).Height(height).Render(styleDim.Render(theme.Truncate(sec.Header, inner)) +
+			"\n" + empty)
+	}
 
 	// Show section full name as a dim header
-	header := styleDim.Render(theme.Truncate(sec.fullName, inner))
+	header := styleDim.Render(theme.Truncate(sec.Header, inner))
 	headerLines := 1
-	pkgH := height - headerLines - 1
+
+	// The focused entry's comments sit under the list, capped so they never
+	// crowd it out.
+	var notes []string
+	if e := m.currentEntry(); e != nil && !m.leftFocus {
+		notes = noteLines(e, inner)
+		if limit := max(0, (height-headerLines)/3); len(notes) > limit {
+			notes = notes[len(notes)-limit:]
+		}
+	}
+	pkgH := height - headerLines - 1 - len(notes)
+	if len(notes) > 0 {
+		pkgH--
+	}
 	if pkgH < 1 {
 		pkgH = 1
 	}
@@ -1184,18 +1637,20 @@ func (m model) viewRight(inner, height int) string {
 		start = m.entIdx - pkgH + 1
 	}
 
-	// Column widths: cursor(2) + name(nameW) + gap(2) + kind(4) + greedy(2) + gap(2) + desc(rest)
-	const kindW = 4
-	const greedyW = 2
-	const fixedOverhead = 2 + 2 + kindW + greedyW + 2 // cursor + gap + kind + greedy + gap
+	// Column widths: cursor(2) + status(2) + name(nameW) + gap(2) + kind(kindW) + greedy(2) + gap(2) + detail(rest)
+	// The detail column holds version state and options first, then the description.
+	const greedyW, statusW = 2, 2
 
-	// Size name column to longest name in section, capped at 35
-	maxNameLen := 0
-	for _, e := range sec.entries {
-		if l := len([]rune(e.name)); l > maxNameLen {
+	// Size name column to longest name in section, capped at 35, and the
+	// kind column to the longest directive ("whalebrew" is 9).
+	maxNameLen, kindW := 0, 4
+	for _, e := range ents {
+		if l := len([]rune(e.Name)); l > maxNameLen {
 			maxNameLen = l
 		}
+		kindW = max(kindW, len(e.Kind.String()))
 	}
+	fixedOverhead := 2 + statusW + 2 + kindW + greedyW + 2 // cursor + status + gap + kind + greedy + gap
 	nameW := min(maxNameLen, 35)
 	descW := inner - nameW - fixedOverhead
 	if descW < 0 {
@@ -1208,37 +1663,64 @@ func (m model) viewRight(inner, height int) string {
 
 	var sb strings.Builder
 	written := 0
-	for i, e := range sec.entries {
+	for i, e := range ents {
 		if i < start || written >= pkgH {
 			continue
 		}
 
 		isCursor := i == m.entIdx && !m.leftFocus
+		mark := " "
+		if m.isMarked(e) {
+			mark = styleCount.Render("●")
+		}
 
-		name := theme.Truncate(e.name, nameW)
+		name := theme.Truncate(e.Name, nameW)
 		name = padRight(name, nameW)
 
-		kindBadge := styleDim.Render(padRight(e.kind.String(), kindW))
+		kindBadge := styleDim.Render(padRight(e.Kind.String(), kindW))
 		greedyMark := "  "
-		if e.greedy {
+		if e.Greedy() {
 			greedyMark = styleGreedy.Render("◆ ")
 		}
 
+		// Profile tags and version state (outdated, pinned) lead the
+		// detail column.
+		state := ""
+		if len(e.Profiles) > 0 && descW > 0 {
+			state = "  " + styleOpts.Render("@"+strings.Join(e.Profiles, ","))
+		}
+		if st := m.statusDetail(e); st != "" && descW > 0 {
+			state += "  " + st
+		}
 		desc := ""
-		if descW > 0 {
-			if d, ok := m.descCache[e.name]; ok {
-				desc = "  " + styleDim.Render(theme.Truncate(d, descW))
+		if restW := descW - lipgloss.Width(state); restW > 0 {
+			sum := optionSummary(e)
+			d := m.descCache[e.Name]
+			switch {
+			case sum != "" && d != "":
+				sumW := min(len([]rune(sum)), restW/2)
+				desc = "  " + styleOpts.Render(theme.Truncate(sum, sumW)) +
+					"  " + styleDim.Render(theme.Truncate(d, max(1, restW-sumW-2)))
+			case sum != "":
+				desc = "  " + styleOpts.Render(theme.Truncate(sum, restW))
+			case d != "":
+				desc = "  " + styleDim.Render(theme.Truncate(d, restW))
 			}
 		}
+		desc = state + desc
 
 		var line string
 		if isCursor {
-			line = styleEntCursor.Render("▸ ") +
+			line = styleEntCursor.Render("▸") + mark + m.statusBadge(e) +
 				styleEntCursor.Render(name) + "  " +
 				kindBadge + greedyMark + desc
 		} else {
-			line = "  " +
-				styleEntNorm.Render(name) + "  " +
+			nameStyle := styleEntNorm
+			if !e.InProfile(m.profile) {
+				nameStyle = styleDim
+			}
+			line = " " + mark + m.statusBadge(e) +
+				nameStyle.Render(name) + "  " +
 				kindBadge + greedyMark + desc
 		}
 		sb.WriteString(line + "\n")
@@ -1246,6 +1728,9 @@ func (m model) viewRight(inner, height int) string {
 	}
 
 	content := header + "\n" + strings.TrimRight(sb.String(), "\n")
+	if len(notes) > 0 {
+		content += "\n" + strings.Repeat("\n", max(0, pkgH-written)) + strings.Join(notes, "\n")
+	}
 	return pane.Width(inner).Height(height).Render(content)
 }
 
@@ -1272,26 +1757,66 @@ func (m model) viewSearch(bodyH int) string {
 			sb.WriteString(styleDim.Render("no matches"))
 		}
 	} else {
-		nameW := inner - 20
-		if nameW < 10 {
-			nameW = 10
-		}
+		// Columns: cursor(2) + name + gap(2) + kind(9) + gap(2) + section(16) + gap(2) + description(rest)
+		const secW = 16
+		nameW := 10
+		for _, r := range m.searchResults {
+			nameW = max(nameW, len([]rune(r.name)))
+		}
+		nameW = min(nameW, 35, max(10, inner-31))
+		descW := inner - 2 - nameW - 2 - 9 - 2 - secW - 2
 		for i, r := range m.searchResults {
 			if i < start || written >= paneH {
 				continue
 			}
-			isCursor := i == m.searchIdx
-			name := padRight(theme.Truncate(r.name, nameW), nameW)
-			sec := theme.Truncate(r.sec, 16)
-			kind := styleDim.Render(padRight(r.kind.String(), 4))
-
-			var line string
-			if isCursor {
-				line = styleSearchHit.Render("▸ "+name) + "  " + kind + "  " + styleSearchSec.Render(sec)
+			sb.WriteString(searchLine(r, i == m.searchIdx, nameW, secW, descW) + "\n")
+			written++
+		}
+	}
+
+	content := strings.TrimRight(sb.String(), "\n")
+	return theme.StylePaneOn.Width(inner).Height(paneH).Render(content)
+}
+
+func (m model) viewAddSearch(bodyH int) string {
+	paneH := max(bodyH-2, 1)
+	inner := max(m.width-4, 10)
+
+	target := "the section picker"
+	if sec := m.currentSection(); sec != nil {
+		target = sec.Name
+	}
+	var sb strings.Builder
+	sb.WriteString(styleInputPfx.Render(" add › Homebrew matches") +
+		styleDim.Render(" — ↓ to pick, enter adds to "+target) + "\n")
+	written := 1
+
+	if len(m.addResults) == 0 {
+		if strings.TrimSpace(m.inputBuf) != "" {
+			sb.WriteString(styleDim.Render("no matches — enter looks up the name as typed"))
+		}
+	} else {
+		nameW := min(max(inner/3, 10), 32)
+		start := 0
+		if m.addResIdx >= paneH-1 {
+			start = m.addResIdx - paneH + 2
+		}
+		for i, r := range m.addResults {
+			if i < start || written >= paneH {
+				continue
+			}
+			desc := r.Desc
+			if d, ok := m.descCache[r.Name]; ok && d != "" {
+				desc = d
+			}
+			name := padRight(theme.Truncate(r.Name, nameW), nameW)
+			kind := styleDim.Render(padRight(r.Kind.String(), 6))
+			desc = styleDim.Render(theme.Truncate(desc, max(inner-nameW-12, 0)))
+			if i == m.addResIdx {
+				sb.WriteString(styleSearchHit.Render("▸ "+name) + "  " + kind + "  " + desc + "\n")
 			} else {
-				line = "  " + styleEntNorm.Render(name) + "  " + kind + "  " + styleSearchSec.Render(sec)
+				sb.WriteString("  " + styleEntNorm.Render(name) + "  " + kind + "  " + desc + "\n")
 			}
-			sb.WriteString(line + "\n")
 			written++
 		}
 	}
@@ -1300,59 +1825,91 @@ func (m model) viewSearch(bodyH int) string {
 	return theme.StylePaneOn.Width(inner).Height(paneH).Render(content)
 }
 
-func (m model) viewPrune(bodyH int) string {
+func (m model) viewDiff(bodyH int) string {
 	inner := m.width - 4
 	paneH := bodyH - 2
 	if paneH < 1 {
 		paneH = 1
 	}
 
-	if m.pruneLoading {
+	title := " diff › unsaved changes:"
+	if m.diffCommit {
+		title = " commit › changes to be committed:"
+	}
+	if len(m.diff) == 0 {
 		return theme.StylePaneOn.Width(inner).Height(paneH).
-			Render(styleDim.Render("checking installed packages…"))
+			Render(styleInputPfx.Render(title) + "\n" + styleDim.Render("no changes against HEAD"))
 	}
 
-	if len(m.pruneList) == 0 {
-		return theme.StylePaneOn.Width(inner).Height(paneH).
-			Render(styleFlash.Render("✓ all Brewfile entries are installed — nothing to prune"))
+	var sb strings.Builder
+	sb.WriteString(styleInputPfx.Render(title))
+	if len(m.diff) > paneH-1 {
+		sb.WriteString(styleDim.Render(fmt.Sprintf("  %d–%d of %d",
+			m.diffTop+1, min(m.diffTop+paneH-1, len(m.diff)), len(m.diff))))
+	}
+	sb.WriteString("\n")
+	for i := m.diffTop; i < len(m.diff) && i < m.diffTop+paneH-1; i++ {
+		line := theme.Truncate(strings.TrimRight(m.diff[i], "\r"), inner)
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			line = styleDim.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			line = styleOpts.Render(line)
+		case strings.HasPrefix(line, "+"):
+			line = styleDiffAdd.Render(line)
+		case strings.HasPrefix(line, "-"):
+			line = styleDelete.Render(line)
+		default:
+			line = styleEntNorm.Render(line)
+		}
+		sb.WriteString(line + "\n")
 	}
+	content := strings.TrimRight(sb.String(), "\n")
+	return theme.StylePaneOn.Width(inner).Height(paneH).Render(content)
+}
 
-	header := styleInputPfx.Render(" prune › uninstalled entries:")
-	nameW := inner - 20
-	if nameW < 10 {
-		nameW = 10
+func (m model) viewOptions(bodyH int) string {
+	inner := m.width - 4
+	paneH := bodyH - 2
+	if paneH < 1 {
+		paneH = 1
 	}
 
-	start := 0
-	if m.pruneIdx >= paneH-1 {
-		start = m.pruneIdx - paneH + 2
+	e := m.currentEntry()
+	if e == nil {
+		return theme.StylePaneOn.Width(inner).Height(paneH).Render(styleDim.Render("no entry selected"))
 	}
 
 	var sb strings.Builder
-	sb.WriteString(header + "\n")
-	written := 1
-	for i, p := range m.pruneList {
-		if i < start || written >= paneH {
-			continue
-		}
-		isCursor := i == m.pruneIdx
-		checkbox := "[ ]"
-		if p.marked {
-			checkbox = styleDelete.Render("[✕]")
-		}
-		name := padRight(theme.Truncate(p.name, nameW), nameW)
-		kind := styleDim.Render(padRight(p.kind.String(), 4))
-		sec := styleSearchSec.Render(theme.Truncate(p.sec, 16))
+	sb.WriteString(styleInputPfx.Render(fmt.Sprintf(" options › %s \"%s\"", e.Kind, e.Name)) + "\n")
+	sb.WriteString(styleDim.Render("   "+theme.Truncate(strings.TrimSpace(e.Format()), inner-3)) + "\n\n")
+	written := 3
 
+	if len(e.Args) > 0 {
+		sb.WriteString(styleDim.Render("   args: "+theme.Truncate(strings.Join(e.Args, ", "), inner-9)) + "\n")
+		written++
+	}
+	if len(e.Options) == 0 {
+		sb.WriteString(styleDim.Render("   no options — [a] to add one"))
+	}
+
+	keyW := 0
+	for _, o := range e.Options {
+		keyW = max(keyW, len(o.Key))
+	}
+	for i, o := range e.Options {
+		if written >= paneH {
+			break
+		}
+		k := padRight(o.Key+":", keyW+1)
+		v := theme.Truncate(o.Value, max(1, inner-keyW-6))
 		var line string
-		if isCursor {
-			line = styleEntCursor.Render("▸ ") + checkbox + " " +
-				styleEntCursor.Render(name) + "  " + kind + "  " + sec
+		if i == m.optIdx {
+			line = styleEntCursor.Render("▸ "+k) + " " + styleOpts.Render(v)
 		} else {
-			line = "  " + checkbox + " " +
-				styleEntNorm.Render(name) + "  " + kind + "  " + sec
+			line = "  " + styleEntNorm.Render(k) + " " + styleOpts.Render(v)
 		}
-		sb.WriteString(line + "\n")
+		sb.WriteString(" " + line + "\n")
 		written++
 	}
 
@@ -1380,9 +1937,9 @@ func (m model) viewSectionPicker(label string, cursor int, bodyH int) string {
 		if i < start || written >= paneH {
 			continue
 		}
-		badge := styleBadge.Render(fmt.Sprintf("(%d)", len(sec.entries)))
+		badge := styleBadge.Render(fmt.Sprintf("(%d)", len(sec.Entries)))
 		nameW := inner - lipgloss.Width(badge) - 4
-		name := theme.Truncate(sec.name, nameW)
+		name := theme.Truncate(sec.Name, nameW)
 
 		var line string
 		if i == cursor {
@@ -1413,36 +1970,100 @@ func usage() {
 
 Usage:
   bf [path]           Open the TUI (defaults to ~/mrk/Brewfile)
+  bf --watch [path]   Open the TUI and follow changes other tools make to the file
+  bf --dry-run [path] Open the TUI; i/U/x show the commands instead of running them
+  bf --profile P [path]
+                      Open the TUI for profile P instead of $MRK_PROFILE or the hostname
+  bf <command> ...    Edit the Brewfile without the TUI (see below)
   bf --help           Show this help
 
+Commands (all accept --file PATH and --kind K or --brew/--cask/--tap/…):
+  add <name>...       Add alphabetically [--section S] [--greedy] [--id N] [--opt 'key: value']
+                      [--profile 'P ...'] (tag the new entries for those profiles)
+  rm <name>...        Remove entries
+  mv <name>...        Move entries --section S
+  ls                  List entries as section<TAB>kind<TAB>name [--json] [--section S]
+                      [--profile P] (default: the active profile; "all" lists every entry)
+  greedy on|off <cask>...
+                      Turn greedy: true on or off
+  lint [--fix]        Report unsorted and empty sections, duplicates, misplaced greedy,
+                      unknown options and unused taps; --fix sorts every section
+  lock [--check]      Record installed versions in Brewfile.lock.json; --check reports
+                      entries whose installed version differs from it [--profile P]
+
 TUI keys:
   ↑/↓  k/j           Navigate sections (left) or packages (right)
   ←/→  h/l           Switch panes
   tab / shift+tab     Switch panes
-  a                   Add a package
-  d                   Delete selected package
+  enter               Details for the selected package: homepage, version, dependencies,
+                      what in the Brewfile uses it, caveats, size and tap (brew info,
+                      cached for a week; r refreshes, j/k step through entries)
+  a                   Add a brew, cask, tap, mas, vscode or whalebrew entry
+  d                   Delete selected package (left pane: delete or merge the section)
+  n / r               New section after this one / rename this section
+  J / K               Move this section down / up, with its entries and comments
   m                   Move package to another section
   g                   Toggle greedy: true (casks only)
-  /                   Search packages
-  w                   Write (save) changes to disk
-  c                   Commit saved changes via git
-  q / esc             Quit
+  o                   Edit the entry's options (args:, link:, id:, …)
+  #                   Edit the entry's comments (the # lines above it and the inline one)
+  space / V / *       Mark the package / start or end a range / mark the whole section;
+                      d, m, g, @, i, U and x then apply to every marked entry, esc clears
+  @                   Tag the entry for profiles (# @profile studio); empty clears the tag
+  P                   Show every profile's entries, or only the active profile's
+  f                   Show only missing (✗) or outdated (↑) entries
+  L                   Lint the Brewfile; enter goes to an issue, s sorts every section
+  H                   History: commits that changed the Brewfile (git log --follow) with
+                      the packages each one added, removed or changed; r reverts one
+  p                   Drift: entries not installed (delete or install them),
+                      installed packages not in the Brewfile (add or ignore them) and
+                      orphaned formulae nothing in the Brewfile needs (uninstall them)
+  i / U / x           Install, upgrade or uninstall the selected entry on this Mac
+  u / ctrl+r          Undo / redo the last edit
+  v                   View unsaved changes as a diff
+  /                   Search names, sections and descriptions (fuzzy)
+  w                   Write (save) changes to disk; if the file changed on disk,
+                      choose reload, merge or overwrite
+  c                   Review the diff against HEAD, then save and commit via git
+  q / esc             Quit (asks first when there are unsaved changes)
 `)
 	fmt.Printf("\nVersion: %s (%s)\n", Version, GitSHA)
 }
 
 func main() {
+	if len(os.Args) > 1 && isSubcommand(os.Args[1]) {
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	path := defaultBrewfilePath()
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	watch, dryRun := false, false
+	profile := bfile.ActiveProfile()
+	args := os.Args[1:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if p, ok := strings.CutPrefix(arg, "--profile="); ok {
+			profile = strings.ToLower(p)
+			continue
+		}
+		switch arg {
 		case "--help", "-h":
 			usage()
 			os.Exit(0)
 		case "--version":
 			fmt.Printf("bf %s (%s)\n", Version, GitSHA)
 			os.Exit(0)
+		case "--watch":
+			watch = true
+		case "--dry-run":
+			dryRun = true
+		case "--profile":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "bf: --profile needs a name")
+				os.Exit(2)
+			}
+			i++
+			profile = strings.ToLower(args[i])
 		default:
-			path = os.Args[1]
+			path = arg
 		}
 	}
 
@@ -1451,7 +2072,7 @@ func main() {
 		fmt.Fprintf(os.Stderr, "bf: cannot load Brewfile: %v\n", err)
 		os.Exit(1)
 	}
-	if len(bf.sections) == 0 {
+	if len(bf.doc.Entries()) == 0 {
 		fmt.Fprintln(os.Stderr, "bf: no packages found in Brewfile")
 		os.Exit(1)
 	}
@@ -1463,8 +2084,12 @@ func main() {
 	}
 	defer tty.Close()
 
+	m := newModel(bf)
+	m.watch = watch
+	m.dryRun = dryRun
+	m.profile = profile
 	p := tea.NewProgram(
-		newModel(bf),
+		m,
 		tea.WithAltScreen(),
 		tea.WithInput(tty),
 		tea.WithOutput(tty),
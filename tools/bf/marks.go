@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ── Marks ─────────────────────────────────────────────────────────────────
+
+// entryKey names an entry across reloads; *entry pointers go stale after
+// every edit, so marks are kept by kind and name.
+type entryKey struct {
+	kind pkgKind
+	name string
+}
+
+func keyOf(e *entry) entryKey { return entryKey{e.Kind, e.Name} }
+
+// inVisual reports whether e is inside the visual range that runs from the
+// anchor to the cursor in the current section.
+func (m model) inVisual(e *entry) bool {
+	if !m.visual {
+		return false
+	}
+	lo, hi := min(m.visualAnchor, m.entIdx), max(m.visualAnchor, m.entIdx)
+	ents := m.entries(m.currentSection())
+	for i := lo; i <= hi && i < len(ents); i++ {
+		if ents[i] == e {
+			return true
+		}
+	}
+	return false
+}
+
+func (m model) isMarked(e *entry) bool {
+	return m.marks[keyOf(e)] || m.inVisual(e)
+}
+
+// markedEntries returns the marked entries, the visual range included, in
+// file order.
+func (m model) markedEntries() []*entry {
+	var out []*entry
+	for _, e := range m.bf.doc.Entries() {
+		if m.isMarked(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// commitVisual turns the visual range into ordinary marks.
+func (m model) commitVisual() model {
+	if !m.visual {
+		return m
+	}
+	marks := m.copyMarks()
+	for _, e := range m.entries(m.currentSection()) {
+		if m.inVisual(e) {
+			marks[keyOf(e)] = true
+		}
+	}
+	m.marks = marks
+	m.visual = false
+	return m
+}
+
+// copyMarks returns a writable copy, so an older model value never sees a
+// later model's marks change.
+func (m model) copyMarks() map[entryKey]bool {
+	out := make(map[entryKey]bool, len(m.marks))
+	for k, v := range m.marks {
+		if v {
+			out[k] = true
+		}
+	}
+	return out
+}
+
+func (m model) clearMarks() model {
+	m.marks = nil
+	m.visual = false
+	return m
+}
+
+// toggleMark marks or unmarks the entry under the cursor and steps down, so
+// holding space marks a run.
+func (m model) toggleMark() model {
+	if m.visual {
+		return m.commitVisual()
+	}
+	e := m.currentEntry()
+	if e == nil {
+		return m
+	}
+	marks := m.copyMarks()
+	if marks[keyOf(e)] {
+		delete(marks, keyOf(e))
+	} else {
+		marks[keyOf(e)] = true
+	}
+	m.marks = marks
+	if m.entIdx < len(m.entries(m.currentSection()))-1 {
+		m.entIdx++
+	}
+	return m
+}
+
+// markSection marks every entry shown in the current section, or unmarks
+// them all when they are already marked.
+func (m model) markSection() model {
+	m = m.commitVisual()
+	ents := m.entries(m.currentSection())
+	all := len(ents) > 0
+	for _, e := range ents {
+		all = all && m.marks[keyOf(e)]
+	}
+	marks := m.copyMarks()
+	for _, e := range ents {
+		if all {
+			delete(marks, keyOf(e))
+		} else {
+			marks[keyOf(e)] = true
+		}
+	}
+	m.marks = marks
+	return m
+}
+
+// ── Bulk edits ────────────────────────────────────────────────────────────
+
+// Each bulk edit takes one checkpoint, so a single u undoes all of it.
+
+// sectionOf returns the section that holds e.
+func (bf *brewfile) sectionOf(e *entry) *section {
+	for _, s := range bf.sections {
+		if slices.Contains(s.Entries, e) {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m model) deleteMarked() model {
+	targets := m.markedEntries()
+	if len(targets) == 0 {
+		return m
+	}
+	m.checkpoint(fmt.Sprintf("remove %d entries", len(targets)))
+	// Bottom up, so the lines of the entries still to go do not shift.
+	for i := len(targets) - 1; i >= 0; i-- {
+		m.bf.deleteEntry(targets[i])
+	}
+	m.dirty = true
+	m.flash = fmt.Sprintf("removed %d entries", len(targets))
+	m = m.clearMarks()
+	m.clampCursor()
+	return m
+}
+
+func (m model) moveMarked(targetSec string) model {
+	var keys []entryKey
+	for _, e := range m.markedEntries() {
+		if sec := m.bf.sectionOf(e); sec == nil || sec.Name != targetSec {
+			keys = append(keys, keyOf(e))
+		}
+	}
+	if len(keys) == 0 {
+		m.flash = "already in that section"
+		return m
+	}
+	m.checkpoint(fmt.Sprintf("move %d entries → %s", len(keys), targetSec))
+	for _, k := range keys {
+		if e := m.bf.doc.Find(k.kind, k.name); e != nil {
+			m.bf.moveEntry(e, targetSec)
+		}
+	}
+	m.dirty = true
+	m.flash = fmt.Sprintf("moved %d entries → %s", len(keys), targetSec)
+	m = m.clearMarks()
+	if moved := m.bf.doc.Find(keys[0].kind, keys[0].name); moved != nil {
+		m = m.jumpTo(moved)
+	}
+	return m
+}
+
+// greedyMarked turns greedy on for every marked cask, or off when all of
+// them already have it.
+func (m model) greedyMarked() model {
+	var casks []entryKey
+	on := false
+	for _, e := range m.markedEntries() {
+		if e.Kind == kindCask {
+			casks = append(casks, keyOf(e))
+			on = on || !e.Greedy()
+		}
+	}
+	if len(casks) == 0 {
+		m.flash = "greedy only applies to casks"
+		return m
+	}
+	state := "off"
+	if on {
+		state = "on"
+	}
+	m.checkpoint(fmt.Sprintf("greedy %s for %d casks", state, len(casks)))
+	for _, k := range casks {
+		if e := m.bf.doc.Find(k.kind, k.name); e != nil {
+			m.bf.setGreedy(e, on)
+		}
+	}
+	m.dirty = true
+	m.flash = fmt.Sprintf("greedy %s for %d casks", state, len(casks))
+	m = m.clearMarks()
+	m.clampCursor()
+	return m
+}
+
+// handleMarkKey runs the mark-mode keys; handled is false for keys that are
+// not about marks.
+func (m model) handleMarkKey(key string) (_ model, handled bool) {
+	if m.leftFocus {
+		return m, false
+	}
+	switch key {
+	case "tab", "shift+tab", "left", "h":
+		// The range only makes sense in this section; keep it as marks.
+		return m.commitVisual(), false
+	case " ":
+		return m.toggleMark(), true
+	case "V":
+		if m.visual {
+			return m.commitVisual(), true
+		}
+		if m.currentEntry() != nil {
+			m.visual = true
+			m.visualAnchor = m.entIdx
+		}
+		return m, true
+	case "*":
+		return m.markSection(), true
+	case "esc":
+		if m.visual || len(m.marks) > 0 {
+			m = m.clearMarks()
+			m.flash = "marks cleared"
+			return m, true
+		}
+	}
+	return m, false
+}
+
+// markSummary is the footer note for a non-empty marked set.
+func (m model) markSummary() string {
+	n := len(m.markedEntries())
+	if n == 0 {
+		return ""
+	}
+	mode := ""
+	if m.visual {
+		mode = " (visual)"
+	}
+	return styleCount.Render(fmt.Sprintf("  %d marked%s", n, mode))
+}
+
+// hasMarks reports whether the next bulk-capable key should use the marks.
+func (m model) hasMarks() bool {
+	return len(m.markedEntries()) > 0
+}
+
+// markedNames lists up to three marked names for a prompt.
+func markedNames(targets []*entry) string {
+	var names []string
+	for _, e := range targets[:min(3, len(targets))] {
+		names = append(names, e.Name)
+	}
+	s := strings.Join(names, ", ")
+	if len(targets) > 3 {
+		s += fmt.Sprintf(" and %d more", len(targets)-3)
+	}
+	return s
+}
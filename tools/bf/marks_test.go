@@ -0,0 +1,74 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBulkMoveIsOneUndoStep(t *testing.T) {
+	m := fixtureModel(t)
+	orig := slices.Clone(m.bf.lines)
+
+	m = press(m, "j", "l", " ", " ")
+	if n := len(m.markedEntries()); n != 2 {
+		t.Fatalf("marked %d entries, want 2", n)
+	}
+	m = press(m, "m", "j", "enter")
+	casks := m.bf.sections[2]
+	if len(casks.Entries) != 4 || len(m.bf.sections[1].Entries) != 0 {
+		t.Fatalf("move: %q", m.bf.lines)
+	}
+	if m.hasMarks() || m.flash != "moved 2 entries → Casks" {
+		t.Errorf("after move: marks=%v flash=%q", m.marks, m.flash)
+	}
+
+	m = press(m, "u")
+	if !slices.Equal(m.bf.lines, orig) {
+		t.Errorf("one undo did not restore: %q", m.bf.lines)
+	}
+}
+
+func TestVisualRangeDelete(t *testing.T) {
+	m := fixtureModel(t)
+	orig := slices.Clone(m.bf.lines)
+
+	m = press(m, "j", "l", "V", "j")
+	if m.markSummary() == "" || len(m.markedEntries()) != 2 {
+		t.Fatalf("visual range marks %d entries", len(m.markedEntries()))
+	}
+	m = press(m, "d", "y")
+	if m.bf.doc.Find(kindBrew, "ffmpeg") != nil || m.bf.doc.Find(kindBrew, "yt-dlp") != nil {
+		t.Fatalf("delete: %q", m.bf.lines)
+	}
+	if m = press(m, "u"); !slices.Equal(m.bf.lines, orig) || m.flash != "undid: remove 2 entries" {
+		t.Errorf("undo: flash=%q lines=%q", m.flash, m.bf.lines)
+	}
+}
+
+func TestMarkSectionGreedyAndInstall(t *testing.T) {
+	m := fixtureModel(t)
+
+	// firefox is greedy and vlc is not: the first g turns it on for both.
+	m = press(m, "j", "j", "l", "*", "g")
+	for _, name := range []string{"firefox", "vlc"} {
+		if e := m.bf.doc.Find(kindCask, name); e == nil || !e.Greedy() {
+			t.Errorf("%s not greedy", name)
+		}
+	}
+	m = press(m, "*", "g")
+	for _, name := range []string{"firefox", "vlc"} {
+		if e := m.bf.doc.Find(kindCask, name); e == nil || e.Greedy() {
+			t.Errorf("%s still greedy", name)
+		}
+	}
+
+	// Marks span sections; the install batches them by kind.
+	m.dryRun = true
+	m = press(m, "*", "h", "k", "l", " ", "i")
+	if m.flash != "dry run: brew install ffmpeg && brew install --cask firefox vlc" {
+		t.Errorf("flash = %q", m.flash)
+	}
+	if m = press(m, "esc"); m.hasMarks() || m.state != stateNormal {
+		t.Errorf("esc kept marks: %v", m.marks)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	theme "mrk-theme"
+)
+
+// ── Annotations ───────────────────────────────────────────────────────────
+
+// entryBlock is e's annotation lines followed by its own line, which is what
+// moves and deletes carry together.
+func (bf *brewfile) entryBlock(e *entry) []string {
+	return slices.Clone(bf.lines[e.DocLine : e.Line+1])
+}
+
+// setAnnotation rewrites the "#" lines above e and its inline comment.
+func (bf *brewfile) setAnnotation(e *entry, doc []string, comment string) {
+	if e.Line < 0 || e.Line >= len(bf.lines) {
+		return
+	}
+	raw := bf.lines[e.Line]
+	indent := raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+	var block []string
+	for _, d := range doc {
+		if d == "" {
+			block = append(block, indent+"#")
+		} else {
+			block = append(block, indent+"# "+d)
+		}
+	}
+	e.Comment = comment
+	bf.splice(e.DocLine, e.Line+1, append(block, e.Format()))
+}
+
+// noteRows are the editable annotation rows: each "#" line above the entry,
+// then its inline comment.
+func noteRows(e *entry) []string {
+	return append(slices.Clone(e.Doc), e.Comment)
+}
+
+func (m model) handleNotes(key string) (model, tea.Cmd) {
+	e := m.currentEntry()
+	if e == nil {
+		m.state = stateNormal
+		return m, nil
+	}
+	m.flash = ""
+	inline := len(e.Doc) // index of the inline-comment row
+	switch key {
+	case "esc", "q":
+		m.state = stateNormal
+	case "up", "k":
+		if m.noteIdx > 0 {
+			m.noteIdx--
+		}
+	case "down", "j":
+		if m.noteIdx < inline {
+			m.noteIdx++
+		}
+	case "a":
+		m.inputBuf = ""
+		m.noteEditIdx = -1
+		m.state = stateNoteInput
+	case "enter", "e":
+		m.inputBuf = noteRows(e)[m.noteIdx]
+		m.noteEditIdx = m.noteIdx
+		m.state = stateNoteInput
+	case "d", "x":
+		doc, comment := slices.Clone(e.Doc), e.Comment
+		if m.noteIdx == inline {
+			if comment == "" {
+				break
+			}
+			comment = ""
+		} else {
+			doc = slices.Delete(doc, m.noteIdx, m.noteIdx+1)
+		}
+		m.checkpoint(fmt.Sprintf("remove comment from \"%s\"", e.Name))
+		name, kind := e.Name, e.Kind
+		m.bf.setAnnotation(e, doc, comment)
+		m = m.jumpTo(m.bf.doc.Find(kind, name))
+		m.dirty = true
+		m.flash = "removed comment"
+		m.noteIdx = min(m.noteIdx, len(doc))
+	}
+	return m, nil
+}
+
+// applyNoteInput stores the edited row: a new or changed "#" line above the
+// entry, or the inline comment.
+func (m model) applyNoteInput() model {
+	e := m.currentEntry()
+	if e == nil {
+		m.state = stateNormal
+		return m
+	}
+	text := strings.TrimSpace(m.inputBuf)
+	if strings.HasPrefix(text, "#") {
+		m.flash = "invalid comment — leave out the leading #"
+		return m
+	}
+	doc, comment := slices.Clone(e.Doc), e.Comment
+	switch {
+	case m.noteEditIdx < 0:
+		doc = append(doc, text)
+		m.noteIdx = len(doc) - 1
+	case m.noteEditIdx == len(e.Doc):
+		comment = text
+	default:
+		doc[m.noteEditIdx] = text
+	}
+	m.checkpoint(fmt.Sprintf("edit comment on \"%s\"", e.Name))
+	name, kind := e.Name, e.Kind
+	m.bf.setAnnotation(e, doc, comment)
+	m = m.jumpTo(m.bf.doc.Find(kind, name))
+	m.dirty = true
+	m.flash = "saved comment"
+	m.inputBuf = ""
+	m.state = stateNotes
+	return m
+}
+
+// noteLines renders e's annotation for the package pane's detail strip.
+func noteLines(e *entry, width int) []string {
+	var out []string
+	for _, d := range e.Doc {
+		out = append(out, styleNote.Render(theme.Truncate("# "+d, width)))
+	}
+	if e.Comment != "" {
+		out = append(out, styleNote.Render(theme.Truncate("… # "+e.Comment, width)))
+	}
+	return out
+}
+
+func (m model) viewNotes(bodyH int) string {
+	inner := m.width - 4
+	paneH := max(bodyH-2, 1)
+
+	e := m.currentEntry()
+	if e == nil {
+		return theme.StylePaneOn.Width(inner).Height(paneH).Render(styleDim.Render("no entry selected"))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(styleInputPfx.Render(fmt.Sprintf(" comments › %s \"%s\"", e.Kind, e.Name)) + "\n")
+	for _, l := range m.bf.entryBlock(e) {
+		sb.WriteString(styleDim.Render("   "+theme.Truncate(strings.TrimSpace(l), inner-3)) + "\n")
+	}
+	sb.WriteString("\n")
+
+	label := func(i int) string {
+		if i == len(e.Doc) {
+			return "inline"
+		}
+		return "above"
+	}
+	for i, text := range noteRows(e) {
+		shown := text
+		if shown == "" && i == len(e.Doc) {
+			shown = styleDim.Render("(none)")
+		} else {
+			shown = styleNote.Render(theme.Truncate("# "+text, max(1, inner-12)))
+		}
+		tag := padRight(label(i), 7)
+		if i == m.noteIdx {
+			sb.WriteString(" " + styleEntCursor.Render("▸ "+tag) + " " + shown + "\n")
+		} else {
+			sb.WriteString("   " + styleEntNorm.Render(tag) + " " + shown + "\n")
+		}
+	}
+
+	content := strings.TrimRight(sb.String(), "\n")
+	if lipgloss.Height(content) > paneH {
+		content = strings.Join(strings.Split(content, "\n")[:paneH], "\n")
+	}
+	return theme.StylePaneOn.Width(inner).Height(paneH).Render(content)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const notesFixture = `## CLI Tools - Media
+# needed by the podcast scripts
+# keep in sync with ~/bin/rip
+brew "ffmpeg"
+brew "yt-dlp" # pinned upstream
+
+## Casks - General Applications & Utilities
+cask "vlc"
+`
+
+func TestMoveAndDeleteCarryComments(t *testing.T) {
+	m := fixtureModelOf(t, notesFixture)
+
+	m = press(m, "l", "m", "j", "enter")
+	want := `## CLI Tools - Media
+brew "yt-dlp" # pinned upstream
+
+## Casks - General Applications & Utilities
+cask "vlc"
+# needed by the podcast scripts
+# keep in sync with ~/bin/rip
+brew "ffmpeg"
+`
+	if got := fixtureText(m); got != want {
+		t.Fatalf("after move:\n%s", got)
+	}
+
+	m = press(m, "d", "y")
+	if got := fixtureText(m); strings.Contains(got, "podcast") || strings.Contains(got, "ffmpeg") {
+		t.Errorf("delete left the comments behind:\n%s", got)
+	}
+}
+
+func TestEditComments(t *testing.T) {
+	m := fixtureModelOf(t, notesFixture)
+	orig := slices.Clone(m.bf.lines)
+
+	// ffmpeg: rewrite the second line above it, then add an inline comment.
+	m = press(m, "l", "#", "j", "e")
+	m.inputBuf = "keep in sync with rip.sh"
+	m = press(m, "enter", "j", "enter")
+	m.inputBuf = "for the podcast"
+	m = press(m, "enter")
+
+	// yt-dlp: drop its inline comment.
+	m = press(m, "esc", "j", "#", "d")
+
+	want := `## CLI Tools - Media
+# needed by the podcast scripts
+# keep in sync with rip.sh
+brew "ffmpeg"  # for the podcast
+brew "yt-dlp"
+`
+	if got := fixtureText(m); !strings.HasPrefix(got, want) {
+		t.Fatalf("after edits:\n%s", got)
+	}
+	if e := m.currentEntry(); e == nil || e.Name != "yt-dlp" {
+		t.Errorf("cursor left yt-dlp: %+v", e)
+	}
+
+	// A leading # would turn the line into a section header.
+	m = press(m, "a")
+	m.inputBuf = "# Oops"
+	if m = press(m, "enter"); m.state != stateNoteInput || !strings.HasPrefix(m.flash, "invalid") {
+		t.Errorf("header-like comment accepted: state=%v flash=%q", m.state, m.flash)
+	}
+
+	m = press(m, "esc", "esc", "u", "u", "u")
+	if !slices.Equal(m.bf.lines, orig) {
+		t.Errorf("undo: %q", m.bf.lines)
+	}
+}
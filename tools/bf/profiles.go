@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	bfile "mrk-brewfile"
+)
+
+// ── Profiles ──────────────────────────────────────────────────────────────
+
+// setProfiles tags e with profiles, or untags it when profiles is empty. An
+// existing tag is rewritten where it is, in an annotation line or the inline
+// comment; a new one goes inline. The section's tag is left alone.
+func (bf *brewfile) setProfiles(e *entry, profiles []string) {
+	doc, comment := slices.Clone(e.Doc), e.Comment
+	if _, ok := bfile.ProfileTag(comment); !ok {
+		for i, d := range doc {
+			if _, ok := bfile.ProfileTag(d); !ok {
+				continue
+			}
+			if d = bfile.SetProfileTag(d, profiles); d == "" {
+				doc = slices.Delete(doc, i, i+1)
+			} else {
+				doc[i] = d
+			}
+			bf.setAnnotation(e, doc, comment)
+			return
+		}
+	}
+	bf.setAnnotation(e, doc, bfile.SetProfileTag(comment, profiles))
+}
+
+// parseProfiles splits the profile prompt's text into lowercased names.
+func parseProfiles(s string) []string {
+	p, _ := bfile.ProfileTag("@profile " + s)
+	return p
+}
+
+// inProfile reports whether the package pane shows e under the active
+// profile.
+func (m model) inProfile(e *entry) bool {
+	return m.allProfiles || e.InProfile(m.profile)
+}
+
+// profileLabel names the active profile for the header.
+func (m model) profileLabel() string {
+	switch {
+	case m.profile == "":
+		return ""
+	case m.allProfiles:
+		return "profile: all (" + m.profile + ")"
+	default:
+		return "profile: " + m.profile
+	}
+}
+
+// startProfileInput opens the profile prompt for the action targets, filled
+// in with the current entry's own tag.
+func (m model) startProfileInput() model {
+	if len(m.actionTargets()) == 0 {
+		return m
+	}
+	m.inputBuf = ""
+	if e := m.currentEntry(); e != nil {
+		m.inputBuf = strings.Join(e.OwnProfiles(), " ")
+	}
+	m.state = stateProfileInput
+	return m
+}
+
+// applyProfileInput tags the marked entries, or the current one, with the
+// profiles typed at the prompt. An empty prompt removes their tags.
+func (m model) applyProfileInput() model {
+	targets := m.actionTargets()
+	m.state = stateNormal
+	if len(targets) == 0 {
+		return m
+	}
+	profiles := parseProfiles(m.inputBuf)
+	m.inputBuf = ""
+
+	var keys []entryKey
+	for _, e := range targets {
+		keys = append(keys, keyOf(e))
+	}
+	what := fmt.Sprintf("\"%s\"", targets[0].Name)
+	if len(targets) > 1 {
+		what = fmt.Sprintf("%d entries", len(targets))
+	}
+	if len(profiles) == 0 {
+		m.checkpoint("clear profile on " + what)
+		m.flash = "cleared profile on " + what
+	} else {
+		m.checkpoint(fmt.Sprintf("profile %s on %s", strings.Join(profiles, " "), what))
+		m.flash = fmt.Sprintf("profile %s on %s", strings.Join(profiles, " "), what)
+	}
+	for _, k := range keys {
+		if e := m.bf.doc.Find(k.kind, k.name); e != nil {
+			m.bf.setProfiles(e, profiles)
+		}
+	}
+	m.dirty = true
+	m = m.clearMarks()
+
+	// Stay on the entry, showing every profile if it left the active one.
+	if e := m.bf.doc.Find(keys[0].kind, keys[0].name); e != nil {
+		m = m.jumpTo(e)
+	}
+	m.clampCursor()
+	return m
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const profileFixture = `## CLI Tools - Media
+brew "ffmpeg"
+# @profile studio
+brew "sox"
+
+## Audio
+# @profile studio
+
+cask "ilok-license-manager"
+cask "spotify"  # @profile all
+`
+
+func paneNames(m model) []string {
+	var out []string
+	for _, e := range m.entries(m.currentSection()) {
+		out = append(out, e.Name)
+	}
+	return out
+}
+
+func TestProfileFilterAndToggle(t *testing.T) {
+	m := fixtureModelOf(t, profileFixture)
+	m.profile = "laptop"
+
+	if got := paneNames(m); !slices.Equal(got, []string{"ffmpeg"}) {
+		t.Errorf("Media for laptop = %q", got)
+	}
+	m = press(m, "j")
+	if got := paneNames(m); !slices.Equal(got, []string{"spotify"}) {
+		t.Errorf("Audio for laptop = %q", got)
+	}
+
+	m = press(m, "P")
+	if got := paneNames(m); !slices.Equal(got, []string{"ilok-license-manager", "spotify"}) {
+		t.Errorf("Audio for every profile = %q", got)
+	}
+
+	m = press(m, "P")
+	m.profile = "studio"
+	if got := paneNames(m); !slices.Equal(got, []string{"ilok-license-manager", "spotify"}) {
+		t.Errorf("Audio for studio = %q", got)
+	}
+}
+
+func TestTagProfileRewritesTagInPlace(t *testing.T) {
+	m := fixtureModelOf(t, profileFixture)
+	m.profile = "studio"
+	orig := slices.Clone(m.bf.lines)
+
+	// sox's tag sits on the line above it; editing keeps it there.
+	m = press(m, "l", "j", "@")
+	if m.inputBuf != "studio" {
+		t.Fatalf("prompt = %q", m.inputBuf)
+	}
+	m.inputBuf = "studio, Laptop"
+	m = press(m, "enter")
+	if got := m.bf.lines[2]; got != "# @profile studio laptop" {
+		t.Errorf("tag line = %q", got)
+	}
+
+	// An untagged entry gets an inline tag, which may take it out of the
+	// active profile; the cursor follows it.
+	m = press(m, "k", "@")
+	m.inputBuf = "work"
+	m = press(m, "enter")
+	if got := m.bf.lines[1]; got != `brew "ffmpeg"  # @profile work` {
+		t.Errorf("ffmpeg line = %q", got)
+	}
+	if e := m.currentEntry(); e == nil || e.Name != "ffmpeg" || !m.allProfiles {
+		t.Errorf("cursor on %v, allProfiles=%v", e, m.allProfiles)
+	}
+
+	// Clearing removes the tag line altogether.
+	m = press(m, "j", "@")
+	m.inputBuf = ""
+	m = press(m, "enter")
+	if e := m.bf.doc.Find(kindBrew, "sox"); e == nil || len(e.Doc) != 0 || len(e.Profiles) != 0 {
+		t.Errorf("sox after clearing: %+v", e)
+	}
+
+	m = press(m, "u", "u", "u")
+	if !slices.Equal(m.bf.lines, orig) {
+		t.Errorf("after undo: %q", m.bf.lines)
+	}
+}
+
+func TestCLIProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Brewfile")
+	if err := os.WriteFile(path, []byte(profileFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runFixture(t, path, "add", "reaper", "--cask", "--section", "audio", "--profile", "studio laptop"); code != 0 {
+		t.Fatalf("add exited %d: %s", code, out)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `cask "reaper"  # @profile studio laptop`) {
+		t.Errorf("Brewfile after add:\n%s", data)
+	}
+
+	out, code := runFixture(t, path, "ls", "--profile", "laptop")
+	want := "Media\tbrew\tffmpeg\nAudio\tcask\treaper\nAudio\tcask\tspotify\n"
+	if code != 0 || out != want {
+		t.Errorf("ls --profile laptop (%d):\n%s", code, out)
+	}
+	out, _ = runFixture(t, path, "ls", "--profile", "all")
+	if n := strings.Count(out, "\n"); n != 5 {
+		t.Errorf("ls --profile all listed %d entries:\n%s", n, out)
+	}
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	bfile "mrk-brewfile"
+	theme "mrk-theme"
+)
+
+// ── Prune & drift ─────────────────────────────────────────────────────────
+
+// Prune mode shows drift both ways between the Brewfile and this Mac, one
+// tab per direction.
+type pruneTab int
+
+const (
+	tabMissing   pruneTab = iota // in the Brewfile, not installed
+	tabUntracked                 // installed on request, not in the Brewfile
+	tabOrphans                   // installed, neither listed nor needed by what is
+	pruneTabs
+)
+
+func (t pruneTab) String() string {
+	switch t {
+	case tabUntracked:
+		return "not in Brewfile"
+	case tabOrphans:
+		return "orphaned"
+	}
+	return "not installed"
+}
+
+type pruneEntry struct {
+	name   string
+	kind   pkgKind
+	sec    string
+	line   int // a missing entry's line when the list was built
+	marked bool
+	note   string // shown in place of the section, e.g. reverse dependencies
+}
+
+type driftMsg struct {
+	missing   []pruneEntry
+	untracked []pruneEntry
+	orphans   []pruneEntry
+	err       error
+}
+
+// syncIgnorePath is the list of packages scripts/sync never offers; bf
+// honours and extends the same file.
+func syncIgnorePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".mrk", "sync-ignore")
+}
+
+// readSyncIgnore reads one name per line; # starts a comment.
+func readSyncIgnore(path string) map[string]bool {
+	ignored := map[string]bool{}
+	f, err := os.Open(path)
+	if err != nil {
+		return ignored
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		if name := strings.Join(strings.Fields(line), ""); name != "" {
+			ignored[name] = true
+		}
+	}
+	return ignored
+}
+
+// appendSyncIgnore adds names to the ignore file, creating it with the same
+// header scripts/sync writes. Existing lines are never rewritten.
+func appendSyncIgnore(path string, names []string) error {
+	var buf strings.Builder
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		buf.WriteString("# mrk sync ignore list\n#\n" +
+			"# One formula name or cask name per line, with no brew or cask prefix.\n" +
+			"# A # character starts a comment.\n#\n" +
+			"# sync drops these names, so it never offers them as new packages.\n")
+	case err != nil:
+		return err
+	case len(data) > 0 && data[len(data)-1] != '\n':
+		buf.WriteString("\n")
+	}
+	for _, n := range names {
+		buf.WriteString(n + "\n")
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(buf.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fetchDrift compares the Brewfile with what brew reports. mas, vscode and
+// whalebrew entries have no cheap installed check and are never offered.
+// Entries for other profiles are not missing here, but still count as
+// tracked.
+func fetchDrift(brew brewBackend, bf *brewfile, profile, ignorePath string) tea.Cmd {
+	return func() tea.Msg {
+		if brew == nil {
+			return driftMsg{err: errors.New("brew not available")}
+		}
+		st, err := brew.Installed()
+		if err != nil {
+			return driftMsg{err: err}
+		}
+		leaves, err := brew.Leaves()
+		if err != nil {
+			return driftMsg{err: err}
+		}
+
+		var msg driftMsg
+		tracked := map[entryKey]bool{}
+		for _, sec := range bf.sections {
+			for _, e := range sec.Entries {
+				short := e.Name[strings.LastIndex(e.Name, "/")+1:]
+				tracked[entryKey{e.Kind, short}] = true
+				if _, installed, checked := st.of(e); checked && !installed && e.InProfile(profile) {
+					msg.missing = append(msg.missing, pruneEntry{
+						name: e.Name, kind: e.Kind, sec: sec.Name, line: e.Line,
+					})
+				}
+			}
+		}
+
+		ignored := readSyncIgnore(ignorePath)
+		add := func(kind pkgKind, names []string) {
+			slices.Sort(names)
+			for _, n := range names {
+				if !tracked[entryKey{kind, n}] && !ignored[n] {
+					msg.untracked = append(msg.untracked, pruneEntry{name: n, kind: kind})
+				}
+			}
+		}
+		add(kindBrew, leaves)
+		var casks []string
+		for n := range st.Casks {
+			casks = append(casks, n)
+		}
+		add(kindCask, casks)
+
+		// Orphans need the dependency graph; without it the tab stays empty
+		// rather than offering packages something still needs.
+		if deps, err := brew.Deps(); err == nil {
+			msg.orphans = findOrphans(bf, st, deps)
+		}
+		return msg
+	}
+}
+
+// findOrphans lists installed formulae that are not in the Brewfile and
+// that no Brewfile formula or cask needs, directly or through other
+// dependencies. deps maps each installed formula or cask to what it
+// depends on. Each orphan notes what installed packages still use it.
+func findOrphans(bf *brewfile, st *installedState, deps map[string][]string) []pruneEntry {
+	needed := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		for _, d := range deps[name] {
+			if !needed[d] {
+				needed[d] = true
+				visit(d)
+			}
+		}
+	}
+	for _, e := range bf.doc.Entries() {
+		if e.Kind == kindBrew || e.Kind == kindCask {
+			short := e.Name[strings.LastIndex(e.Name, "/")+1:]
+			needed[short] = true
+			visit(short)
+		}
+	}
+
+	usedBy := map[string][]string{}
+	for pkg, ds := range deps {
+		for _, d := range ds {
+			usedBy[d] = append(usedBy[d], pkg)
+		}
+	}
+
+	var names []string
+	for n := range st.Formulae {
+		if !needed[n] {
+			names = append(names, n)
+		}
+	}
+	slices.Sort(names)
+	var out []pruneEntry
+	for _, n := range names {
+		p := pruneEntry{name: n, kind: kindBrew}
+		if users := usedBy[n]; len(users) > 0 {
+			slices.Sort(users)
+			p.note = "used by " + strings.Join(users, ", ")
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// pruneItems is the list the current tab shows.
+func (m *model) pruneItems() *[]pruneEntry {
+	switch m.pruneTab {
+	case tabUntracked:
+		return &m.untracked
+	case tabOrphans:
+		return &m.orphans
+	}
+	return &m.pruneList
+}
+
+func markedPrune(list []pruneEntry) []pruneEntry {
+	var out []pruneEntry
+	for _, p := range list {
+		if p.marked {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (m model) handlePrune(key string) (model, tea.Cmd) {
+	if m.pruneLoading {
+		if key == "esc" || key == "q" {
+			m.state = stateNormal
+		}
+		return m, nil
+	}
+	m.flash = ""
+	list := m.pruneItems()
+	switch key {
+	case "esc", "q":
+		m.state = stateNormal
+	case "tab", "shift+tab", "left", "right", "h", "l":
+		step := pruneTab(1)
+		if key == "shift+tab" || key == "left" || key == "h" {
+			step = pruneTabs - 1
+		}
+		m.pruneTab = (m.pruneTab + step) % pruneTabs
+		m.pruneIdx = 0
+	case "up", "k":
+		if m.pruneIdx > 0 {
+			m.pruneIdx--
+		}
+	case "down", "j":
+		if m.pruneIdx < len(*list)-1 {
+			m.pruneIdx++
+		}
+	case " ":
+		if m.pruneIdx < len(*list) {
+			(*list)[m.pruneIdx].marked = !(*list)[m.pruneIdx].marked
+			if m.pruneIdx < len(*list)-1 {
+				m.pruneIdx++
+			}
+		}
+	case "a":
+		// Toggle all
+		allMarked := true
+		for _, p := range *list {
+			if !p.marked {
+				allMarked = false
+				break
+			}
+		}
+		for i := range *list {
+			(*list)[i].marked = !allMarked
+		}
+	default:
+		switch m.pruneTab {
+		case tabUntracked:
+			return m.handleUntracked(key)
+		case tabOrphans:
+			return m.handleOrphans(key)
+		}
+		return m.handleMissing(key)
+	}
+	return m, nil
+}
+
+// handleOrphans uninstalls the marked orphans, after the usual prompt.
+func (m model) handleOrphans(key string) (model, tea.Cmd) {
+	if key != "enter" && key != "x" {
+		return m, nil
+	}
+	var targets []*entry
+	for _, p := range markedPrune(m.orphans) {
+		targets = append(targets, bfile.NewEntry(p.kind, p.name))
+	}
+	if len(targets) == 0 {
+		m.flash = "nothing selected"
+		return m, nil
+	}
+	m.pendingAct = actUninstall
+	m.pendingTargets = targets
+	m.state = stateActionConfirm
+	return m, nil
+}
+
+// pruneTarget finds p's entry on the line it had when the list was built
+// or, when an adopt or a --watch reload has moved it since, by name. The
+// line matters when the Brewfile lists a package twice.
+func (bf *brewfile) pruneTarget(p pruneEntry) *entry {
+	if e := bf.entryOnLine(p.line); e != nil && e.Kind == p.kind && e.Name == p.name {
+		return e
+	}
+	return bf.doc.Find(p.kind, p.name)
+}
+
+// entryOnLine returns the entry written on line, or nil.
+func (bf *brewfile) entryOnLine(line int) *entry {
+	for _, e := range bf.doc.Entries() {
+		if e.Line == line {
+			return e
+		}
+	}
+	return nil
+}
+
+// handleMissing acts on Brewfile entries that are not installed: delete
+// them from the Brewfile, or install them.
+func (m model) handleMissing(key string) (model, tea.Cmd) {
+	marked := markedPrune(m.pruneList)
+	switch key {
+	case "i":
+		var targets []*entry
+		for _, p := range marked {
+			if e := m.bf.pruneTarget(p); e != nil {
+				targets = append(targets, e)
+			}
+		}
+		if len(targets) == 0 {
+			m.flash = "nothing selected"
+			return m, nil
+		}
+		m.pruneList = nil
+		return m.startAction(actInstall, targets)
+	case "enter", "d":
+		if len(marked) == 0 {
+			m.flash = "nothing selected"
+			m.state = stateNormal
+			return m, nil
+		}
+		m.checkpoint(fmt.Sprintf("prune %d entry/entries", len(marked)))
+		// Resolve every entry before deleting any, then delete from the
+		// bottom up so the lines still to go stay where they are.
+		var lines []int
+		for _, p := range marked {
+			if e := m.bf.pruneTarget(p); e != nil && !slices.Contains(lines, e.Line) {
+				lines = append(lines, e.Line)
+			}
+		}
+		slices.Sort(lines)
+		slices.Reverse(lines)
+		for _, line := range lines {
+			m.bf.deleteEntry(m.bf.entryOnLine(line))
+		}
+		m.dirty = true
+		m.flash = fmt.Sprintf("removed %d uninstalled entry/entries", len(lines))
+		m.pruneList = nil
+		m.clampCursor()
+		m.state = stateNormal
+	}
+	return m, nil
+}
+
+// handleUntracked acts on installed packages the Brewfile does not list:
+// adopt them into a section, or add them to the sync ignore list.
+func (m model) handleUntracked(key string) (model, tea.Cmd) {
+	targets := markedPrune(m.untracked)
+	if len(targets) == 0 && m.pruneIdx < len(m.untracked) {
+		targets = []pruneEntry{m.untracked[m.pruneIdx]}
+	}
+	if len(targets) == 0 {
+		return m, nil
+	}
+	switch key {
+	case "enter", "A":
+		m.moveSecIdx = m.secIdx
+		if sec := m.bf.defaultSection(targets[0].kind); sec != nil {
+			m.moveSecIdx = max(0, slices.Index(m.bf.sections, sec))
+		}
+		m.state = stateAdopt
+	case "I":
+		var names []string
+		for _, p := range targets {
+			names = append(names, p.name)
+		}
+		if err := appendSyncIgnore(m.ignorePath, names); err != nil {
+			m.flash = "ignore failed: " + err.Error()
+			break
+		}
+		m.untracked = slices.DeleteFunc(m.untracked, func(p pruneEntry) bool {
+			return slices.Contains(names, p.name)
+		})
+		m.pruneIdx = min(m.pruneIdx, max(0, len(m.untracked)-1))
+		m.flash = fmt.Sprintf("ignored %d package(s) in %s", len(names), m.ignorePath)
+	}
+	return m, nil
+}
+
+// handleAdopt is the section picker for adopting untracked packages.
+func (m model) handleAdopt(key string) (model, tea.Cmd) {
+	secs := m.bf.sections
+	switch key {
+	case "esc":
+		m.state = statePrune
+	case "up", "k":
+		if m.moveSecIdx > 0 {
+			m.moveSecIdx--
+		}
+	case "down", "j":
+		if m.moveSecIdx < len(secs)-1 {
+			m.moveSecIdx++
+		}
+	case "enter":
+		if m.moveSecIdx >= len(secs) {
+			break
+		}
+		target := secs[m.moveSecIdx].Name
+		adopt := markedPrune(m.untracked)
+		if len(adopt) == 0 && m.pruneIdx < len(m.untracked) {
+			adopt = []pruneEntry{m.untracked[m.pruneIdx]}
+		}
+		m.checkpoint(fmt.Sprintf("adopt %d package(s) into %s", len(adopt), target))
+		for _, p := range adopt {
+			m.bf.addEntry(p.name, p.kind, false, target)
+		}
+		m.dirty = true
+		m.untracked = slices.DeleteFunc(m.untracked, func(p pruneEntry) bool {
+			return slices.ContainsFunc(adopt, func(a pruneEntry) bool { return a.name == p.name && a.kind == p.kind })
+		})
+		m.pruneIdx = min(m.pruneIdx, max(0, len(m.untracked)-1))
+		m.flash = fmt.Sprintf("added %d package(s) to %s", len(adopt), target)
+		m.state = statePrune
+	}
+	return m, nil
+}
+
+func (m model) viewPrune(bodyH int) string {
+	inner := m.width - 4
+	paneH := bodyH - 2
+	if paneH < 1 {
+		paneH = 1
+	}
+
+	if m.pruneLoading {
+		return theme.StylePaneOn.Width(inner).Height(paneH).
+			Render(styleDim.Render("checking installed packages…"))
+	}
+
+	// Tab bar: the active direction is highlighted, each with its count.
+	var tabs []string
+	for t := tabMissing; t < pruneTabs; t++ {
+		view := m
+		view.pruneTab = t
+		n := len(*view.pruneItems())
+		label := fmt.Sprintf(" %s (%d) ", t, n)
+		if t == m.pruneTab {
+			tabs = append(tabs, styleCatActive.Render("▸"+label))
+		} else {
+			tabs = append(tabs, styleCatNorm.Render(" "+label))
+		}
+	}
+	header := styleInputPfx.Render(" drift › ") + lipgloss.JoinHorizontal(lipgloss.Top, tabs...)
+
+	list := *m.pruneItems()
+	if len(list) == 0 {
+		empty := "✓ all Brewfile entries are installed — nothing to prune"
+		switch m.pruneTab {
+		case tabUntracked:
+			empty = "✓ everything installed on request is in the Brewfile"
+		case tabOrphans:
+			empty = "✓ every installed formula is in the Brewfile or needed by it"
+		}
+		return theme.StylePaneOn.Width(inner).Height(paneH).
+			Render(header + "\n\n" + styleFlash.Render(empty))
+	}
+
+	// Columns: cursor(2) + checkbox(4) + name + gap(2) + kind(9) + gap(2) + section or note(rest)
+	nameW := 10
+	for _, p := range list {
+		nameW = max(nameW, len([]rune(p.name)))
+	}
+	nameW = min(nameW, 35)
+	noteW := max(16, inner-2-4-nameW-2-9-2)
+
+	start := 0
+	if m.pruneIdx >= paneH-1 {
+		start = m.pruneIdx - paneH + 2
+	}
+
+	var sb strings.Builder
+	sb.WriteString(header + "\n")
+	written := 1
+	for i, p := range list {
+		if i < start || written >= paneH {
+			continue
+		}
+		isCursor := i == m.pruneIdx
+		checkbox := "[ ]"
+		if p.marked {
+			checkbox = styleDelete.Render("[✕]")
+			if m.pruneTab == tabUntracked {
+				checkbox = styleInstalled.Render("[+]")
+			}
+		}
+		name := padRight(theme.Truncate(p.name, nameW), nameW)
+		kind := styleDim.Render(padRight(p.kind.String(), 9))
+		sec := styleSearchSec.Render(theme.Truncate(p.sec, 16))
+		if p.note != "" {
+			sec = styleDim.Render(theme.Truncate(p.note, noteW))
+		}
+
+		var line string
+		if isCursor {
+			line = styleEntCursor.Render("▸ ") + checkbox + " " +
+				styleEntCursor.Render(name) + "  " + kind + "  " + sec
+		} else {
+			line = "  " + checkbox + " " +
+				styleEntNorm.Render(name) + "  " + kind + "  " + sec
+		}
+		sb.WriteString(line + "\n")
+		written++
+	}
+
+	content := strings.TrimRight(sb.String(), "\n")
+	return theme.StylePaneOn.Width(inner).Height(paneH).Render(content)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// driftBrew has ffmpeg, firefox and the tap installed, plus two formulae
+// and a cask the Brewfile does not list, and their dependencies.
+func driftBrew() *fakeBrew {
+	return &fakeBrew{
+		state: &installedState{
+			Formulae: map[string]pkgState{"ffmpeg": {}, "jq": {}, "sox": {}, "libpng": {}, "x264": {}, "oniguruma": {}},
+			Casks:    map[string]pkgState{"firefox": {}, "zed": {}},
+			Taps:     map[string]bool{"sevmorris/tap": true},
+		},
+		leaves: []string{"sox", "ffmpeg", "jq"},
+		deps: map[string][]string{
+			"ffmpeg": {"libpng", "x264"},
+			"jq":     {"oniguruma"},
+			"sox":    {"libpng"},
+		},
+	}
+}
+
+func openDrift(t *testing.T, m model) model {
+	t.Helper()
+	m.brew = driftBrew()
+	m.ignorePath = filepath.Join(t.TempDir(), "sync-ignore")
+	m, cmd := m.handleKey(keyMsg("p"))
+	m, _ = deliver(t, m, cmd)
+	return m
+}
+
+func pruneNames(list []pruneEntry) []string {
+	var out []string
+	for _, p := range list {
+		out = append(out, p.kind.String()+" "+p.name)
+	}
+	return out
+}
+
+func TestDriftListsBothDirections(t *testing.T) {
+	m := fixtureModel(t)
+	m = openDrift(t, m)
+
+	if got := pruneNames(m.pruneList); !slices.Equal(got, []string{"brew yt-dlp", "cask vlc"}) {
+		t.Errorf("missing = %q", got)
+	}
+	// libpng and x264 are dependencies, not leaves, so they are not offered.
+	if got := pruneNames(m.untracked); !slices.Equal(got, []string{"brew jq", "brew sox", "cask zed"}) {
+		t.Errorf("untracked = %q", got)
+	}
+}
+
+func TestDriftAdoptIntoSection(t *testing.T) {
+	m := fixtureModel(t)
+	m = openDrift(t, m)
+
+	// Mark jq and sox, then add both to Media.
+	m = press(m, "tab", " ", " ", "enter")
+	if m.state != stateAdopt {
+		t.Fatalf("state = %v", m.state)
+	}
+	m = press(m, "enter") // the picker starts on the first section with formulae
+	for _, name := range []string{"jq", "sox"} {
+		e := m.bf.doc.Find(kindBrew, name)
+		if e == nil || m.bf.sectionOf(e).Name != "Media" {
+			t.Errorf("%s not added to Media", name)
+		}
+	}
+	if got := pruneNames(m.untracked); !slices.Equal(got, []string{"cask zed"}) || m.state != statePrune {
+		t.Errorf("after adopt: state=%v untracked=%q", m.state, got)
+	}
+
+	m = press(m, "esc", "u")
+	if m.bf.doc.Find(kindBrew, "jq") != nil || m.bf.doc.Find(kindBrew, "sox") != nil {
+		t.Error("one undo did not remove both")
+	}
+}
+
+func TestDriftDeleteAfterAdoptFindsEntriesAgain(t *testing.T) {
+	m := fixtureModel(t)
+	m = openDrift(t, m)
+
+	// Adopting inserts lines above the missing entries, then delete every
+	// missing one.
+	m = press(m, "tab", " ", " ", "enter", "enter")
+	m = press(m, "tab", "tab", "a", "d")
+
+	for _, name := range []string{"jq", "sox", "ffmpeg"} {
+		if m.bf.doc.Find(kindBrew, name) == nil {
+			t.Errorf("%s was deleted", name)
+		}
+	}
+	if m.bf.doc.Find(kindBrew, "yt-dlp") != nil || m.bf.doc.Find(kindCask, "vlc") != nil {
+		t.Errorf("missing entries were not deleted:\n%s", strings.Join(m.bf.lines, "\n"))
+	}
+	if len(m.bf.sections) == 0 || !slices.ContainsFunc(m.bf.lines, func(l string) bool { return strings.HasPrefix(l, "## ") }) {
+		t.Errorf("section headers lost:\n%s", strings.Join(m.bf.lines, "\n"))
+	}
+}
+
+func TestDriftDeletesTheListedCopyOfADuplicate(t *testing.T) {
+	m := fixtureModelOf(t, cliFixture+"\n## Extras\nbrew \"yt-dlp\"  # second copy\n")
+	m = openDrift(t, m)
+	if got := pruneNames(m.pruneList); !slices.Equal(got, []string{"brew yt-dlp", "cask vlc", "brew yt-dlp"}) {
+		t.Fatalf("missing = %q", got)
+	}
+
+	// Mark only the copy in Extras.
+	m = press(m, "j", "j", " ", "d")
+	e := m.bf.doc.Find(kindBrew, "yt-dlp")
+	if e == nil || e.Comment != "" || m.bf.sectionOf(e).Name != "Media" {
+		t.Errorf("wrong copy deleted:\n%s", strings.Join(m.bf.lines, "\n"))
+	}
+	if m.flash != "removed 1 uninstalled entry/entries" {
+		t.Errorf("flash = %q", m.flash)
+	}
+}
+
+func TestDriftIgnoreUsesSyncIgnore(t *testing.T) {
+	m := fixtureModel(t)
+	m = openDrift(t, m)
+	if err := os.WriteFile(m.ignorePath, []byte("# mine\nsox  # keep local\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m = press(m, "esc")
+	m, cmd := m.handleKey(keyMsg("p"))
+	m, _ = deliver(t, m, cmd)
+	if got := pruneNames(m.untracked); !slices.Equal(got, []string{"brew jq", "cask zed"}) {
+		t.Fatalf("untracked with sox ignored = %q", got)
+	}
+
+	m = press(m, "tab", "j", "I")
+	data, _ := os.ReadFile(m.ignorePath)
+	if got := string(data); got != "# mine\nsox  # keep local\nzed\n" {
+		t.Errorf("ignore file:\n%s", got)
+	}
+	if got := pruneNames(m.untracked); !slices.Equal(got, []string{"brew jq"}) {
+		t.Errorf("untracked = %q", got)
+	}
+}
+
+func TestAppendSyncIgnoreCreatesHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".mrk", "sync-ignore")
+	if err := appendSyncIgnore(path, []string{"nvm"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "# mrk sync ignore list\n") || !strings.HasSuffix(string(data), "\nnvm\n") {
+		t.Errorf("new ignore file:\n%s", data)
+	}
+	if !readSyncIgnore(path)["nvm"] {
+		t.Error("nvm not read back")
+	}
+}
+
+func TestOrphansRespectDependencies(t *testing.T) {
+	m := fixtureModel(t)
+	m = openDrift(t, m)
+
+	// ffmpeg needs libpng and x264; sox also uses libpng but that does not
+	// make sox needed.
+	var got []string
+	for _, p := range m.orphans {
+		got = append(got, p.name+" "+p.note)
+	}
+	want := []string{"jq ", "oniguruma used by jq", "sox "}
+	if !slices.Equal(got, want) {
+		t.Errorf("orphans = %q, want %q", got, want)
+	}
+
+	m.dryRun = true
+	m = press(m, "tab", "tab", " ", " ", "enter")
+	if m.state != stateActionConfirm {
+		t.Fatalf("state = %v", m.state)
+	}
+	if m = press(m, "n"); m.state != statePrune {
+		t.Errorf("cancel went to %v", m.state)
+	}
+	m = press(m, "x", "y")
+	if m.flash != "dry run: brew uninstall jq oniguruma" {
+		t.Errorf("flash = %q", m.flash)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ── Search ────────────────────────────────────────────────────────────────
+
+// searchResult is one entry that matched the query, with the rune positions
+// that matched in each field so viewSearch can highlight them.
+type searchResult struct {
+	secIdx int
+	entIdx int
+	name   string
+	sec    string
+	desc   string
+	kind   pkgKind
+	greedy bool
+
+	score                    int
+	nameHit, secHit, descHit []int
+}
+
+// Field weights: a name match outranks the same match in a section name,
+// which outranks one in a description.
+const (
+	nameWeight = 3
+	secWeight  = 2
+	descWeight = 1
+)
+
+// search matches query against every entry's name, section name and cached
+// description, best first. Entries that score the same keep file order.
+func (bf *brewfile) search(query string, descs map[string]string) []searchResult {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+	var results []searchResult
+	for si, s := range bf.sections {
+		for ei, e := range s.Entries {
+			r := searchResult{
+				secIdx: si, entIdx: ei,
+				name: e.Name, sec: s.Name, desc: descs[e.Name],
+				kind: e.Kind, greedy: e.Greedy(),
+			}
+			var sc int
+			if sc, r.nameHit = fuzzyMatch(query, r.name, false); r.nameHit != nil {
+				r.score = max(r.score, sc*nameWeight)
+			}
+			if sc, r.secHit = fuzzyMatch(query, r.sec, true); r.secHit != nil {
+				r.score = max(r.score, sc*secWeight)
+			}
+			if sc, r.descHit = fuzzyMatch(query, r.desc, true); r.descHit != nil {
+				r.score = max(r.score, sc*descWeight)
+			}
+			if r.score > 0 {
+				results = append(results, r)
+			}
+		}
+	}
+	slices.SortStableFunc(results, func(a, b searchResult) int { return b.score - a.score })
+	return results
+}
+
+// fuzzyMatch finds pattern in text as a case-insensitive subsequence and
+// scores it: consecutive runs and word starts count extra, gaps count
+// against. pos holds the matched rune indices, or nil when there is no
+// match. compact rejects matches spread over more than twice the pattern's
+// length, which keeps long prose from matching every short query.
+func fuzzyMatch(pattern, text string, compact bool) (score int, pos []int) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+	if len(p) == 0 || len(p) > len(t) {
+		return 0, nil
+	}
+	// Try every start of the first rune and keep the best greedy match; the
+	// start of an exact substring is one of them.
+	for start := range t {
+		if t[start] != p[0] {
+			continue
+		}
+		sc, hit := scoreFrom(p, t, start)
+		if hit == nil || (compact && hit[len(hit)-1]-hit[0]+1 > 2*len(p)) {
+			continue
+		}
+		if sc > score {
+			score, pos = sc, hit
+		}
+	}
+	return score, pos
+}
+
+func scoreFrom(p, t []rune, start int) (int, []int) {
+	hit := make([]int, 0, len(p))
+	score := 0
+	ti := start
+	for _, r := range p {
+		for ti < len(t) && t[ti] != r {
+			ti++
+		}
+		if ti == len(t) {
+			return 0, nil
+		}
+		score += 10
+		switch {
+		case len(hit) > 0 && hit[len(hit)-1] == ti-1:
+			score += 15
+		case len(hit) > 0:
+			score -= min(ti-hit[len(hit)-1]-1, 10)
+		}
+		if ti == 0 || isWordBreak(t[ti-1]) {
+			score += 10
+		}
+		hit = append(hit, ti)
+		ti++
+	}
+	if hit[0] == 0 {
+		score += 5
+	}
+	if len(hit) == len(t) {
+		score += 20 // the whole text
+	}
+	return score, hit
+}
+
+func isWordBreak(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// highlight renders text in base with the runes at pos in hit, cut to width
+// runes. When the first hit would fall off the end, the window slides so
+// the match stays visible; below three runes there is no room to slide.
+func highlight(text string, pos []int, width int, base, hit lipgloss.Style) string {
+	runes := []rune(text)
+	if width <= 0 {
+		return ""
+	}
+	from := 0
+	if len(pos) > 0 && len(runes) > width && pos[0] >= width-1 && width >= 3 {
+		// Two runes of context before the hit, but never so many that the
+		// hit lands under the closing "…", nor past the end of the text.
+		from = pos[0] - 2
+		if pos[0] >= from+width-2 {
+			from = pos[0] - width + 3
+		}
+		from = max(0, min(from, len(runes)-width+1))
+	}
+	var sb strings.Builder
+	n := 0
+	if from > 0 {
+		sb.WriteString(base.Render("…"))
+		n++
+	}
+	for i := from; i < len(runes) && n < width; i++ {
+		if n == width-1 && i < len(runes)-1 {
+			sb.WriteString(base.Render("…"))
+			n++
+			break
+		}
+		s := string(runes[i])
+		if slices.Contains(pos, i) {
+			sb.WriteString(hit.Render(s))
+		} else {
+			sb.WriteString(base.Render(s))
+		}
+		n++
+	}
+	return sb.String()
+}
+
+// searchLine renders one result row for viewSearch.
+func searchLine(r searchResult, isCursor bool, nameW, secW, descW int) string {
+	nameBase := styleEntNorm
+	if isCursor {
+		nameBase = styleEntCursor
+	}
+	cursor := "  "
+	if isCursor {
+		cursor = styleSearchHit.Render("▸ ")
+	}
+	name := padRight(highlight(r.name, r.nameHit, nameW, nameBase, styleMatch), nameW)
+	kind := styleDim.Render(padRight(r.kind.String(), 9))
+	sec := padRight(highlight(r.sec, r.secHit, secW, styleSearchSec, styleMatch), secW)
+	line := cursor + name + "  " + kind + "  " + sec
+	if descW > 0 && r.desc != "" {
+		line += "  " + highlight(r.desc, r.descHit, descW, styleDim, styleMatch)
+	}
+	return line
+}
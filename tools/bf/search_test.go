@@ -0,0 +1,108 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	cases := []struct {
+		pattern, text string
+		compact       bool
+		want          []int
+	}{
+		{"yt", "yt-dlp", false, []int{0, 1}},
+		{"ydl", "yt-dlp", false, []int{0, 3, 4}},
+		{"FFM", "ffmpeg", false, []int{0, 1, 2}},
+		// The word start beats the earlier scattered letters.
+		{"dl", "audio-dl", false, []int{6, 7}},
+		{"video", "Open-source video transcoder", true, []int{12, 13, 14, 15, 16}},
+		{"video", "very idle daemon output", true, nil},
+		{"zz", "ffmpeg", false, nil},
+	}
+	for _, tc := range cases {
+		_, got := fuzzyMatch(tc.pattern, tc.text, tc.compact)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tc.pattern, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestSearchRanksAcrossFields(t *testing.T) {
+	m := fixtureModel(t)
+	descs := map[string]string{
+		"vlc":     "Multimedia player that fires up anything",
+		"ffmpeg":  "Play, record, convert, and stream audio and video",
+		"firefox": "Web browser",
+	}
+
+	names := func(rs []searchResult) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.name)
+		}
+		return out
+	}
+
+	// "video" is in ffmpeg's description only.
+	if got := names(m.bf.search("video", descs)); !slices.Equal(got, []string{"ffmpeg"}) {
+		t.Errorf("video: %q", got)
+	}
+	// A name match outranks a description match; both are found.
+	if got := names(m.bf.search("fire", descs)); !slices.Equal(got, []string{"firefox", "vlc"}) {
+		t.Errorf("fire: %q", got)
+	}
+	if got := names(m.bf.search("fx", descs)); !slices.Equal(got, []string{"firefox"}) {
+		t.Errorf("fx: %q", got)
+	}
+	// Section names match too: every entry under Media.
+	if got := names(m.bf.search("media", descs)); !slices.Equal(got, []string{"ffmpeg", "yt-dlp", "vlc"}) {
+		t.Errorf("media: %q", got)
+	}
+}
+
+func TestSearchKeysJumpToBestMatch(t *testing.T) {
+	m := fixtureModel(t)
+	m = press(m, "/", "v", "l", "c", "enter")
+	if e := m.currentEntry(); e == nil || e.Name != "vlc" {
+		t.Errorf("jumped to %+v", e)
+	}
+}
+
+func TestHighlightKeepsMatchInView(t *testing.T) {
+	plain := lipgloss.NewStyle()
+	_, pos := fuzzyMatch("video", "Open-source video transcoder", true)
+	if got := highlight("Open-source video transcoder", pos, 12, plain, plain); got != "…e video tr…" {
+		t.Errorf("highlight = %q", got)
+	}
+	if got := highlight("ffmpeg", []int{0}, 12, plain, plain); got != "ffmpeg" {
+		t.Errorf("highlight = %q", got)
+	}
+}
+
+func TestHighlightNarrowWidths(t *testing.T) {
+	plain := lipgloss.NewStyle()
+	cases := []struct {
+		text  string
+		pos   []int
+		width int
+		want  string
+	}{
+		{"abcdef", []int{1}, 2, "a…"},
+		{"abcdef", []int{0}, 1, "…"},
+		{"abcdef", []int{2}, 2, "a…"},
+		{"abcdef", []int{4}, 2, "a…"},
+		{"abcdef", []int{3}, 3, "…d…"},
+		{"abcdef", []int{0, 1}, 3, "ab…"},
+		{"abcdef", []int{5}, 3, "…ef"},
+		{"ab", []int{1}, 2, "ab"},
+		{"abcdef", nil, 0, ""},
+	}
+	for _, tc := range cases {
+		if got := highlight(tc.text, tc.pos, tc.width, plain, plain); got != tc.want {
+			t.Errorf("highlight(%q, %v, %d) = %q, want %q", tc.text, tc.pos, tc.width, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	bfile "mrk-brewfile"
+)
+
+// ── Section editing ───────────────────────────────────────────────────────
+
+// sectionBodyEnd is the index just past s's last non-blank line, so text
+// added to s lands above the blank lines that separate it from the next
+// header.
+func (bf *brewfile) sectionBodyEnd(s *section) int {
+	end := s.End
+	for end > s.Line+1 && strings.TrimSpace(bf.lines[end-1]) == "" {
+		end--
+	}
+	return end
+}
+
+// namedSections are the sections with a "## " header, i.e. all but the
+// implicit leading one.
+func (bf *brewfile) namedSections() []*section {
+	var out []*section
+	for _, s := range bf.doc.Sections {
+		if s.Line >= 0 {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// splice replaces lines[from:to] with repl and re-parses.
+func (bf *brewfile) splice(from, to int, repl []string) {
+	out := make([]string, 0, len(bf.lines)-(to-from)+len(repl))
+	out = append(out, bf.lines[:from]...)
+	out = append(out, repl...)
+	out = append(out, bf.lines[to:]...)
+	bf.lines = out
+	bf.reload()
+}
+
+// createSection adds a "## header" section after the section after, or at
+// the end of the file when after is nil, separated by a blank line.
+func (bf *brewfile) createSection(header string, after *section) {
+	at := len(bf.lines)
+	if after != nil {
+		at = bf.sectionBodyEnd(after)
+	}
+	block := []string{"## " + header}
+	if at > 0 {
+		block = append([]string{""}, block...)
+	}
+	bf.splice(at, at, block)
+}
+
+// renameSection swaps the short name in s's header, keeping any long
+// description and the header's own "##" spacing.
+func (bf *brewfile) renameSection(s *section, name string) {
+	line := bf.lines[s.Line]
+	i := strings.Index(line, s.Header)
+	if i < 0 {
+		return
+	}
+	bf.lines[s.Line] = line[:i] + bfile.RenameHeader(s.Header, name) + line[i+len(s.Header):]
+	bf.reload()
+}
+
+// moveSection swaps s with its neighbour above (delta -1) or below (+1),
+// carrying entries and comments. The blank lines after each section stay in
+// place, so spacing between sections is unchanged.
+func (bf *brewfile) moveSection(s *section, delta int) bool {
+	named := bf.namedSections()
+	i := slices.Index(named, s)
+	j := i + delta
+	if i < 0 || j < 0 || j >= len(named) {
+		return false
+	}
+	a, b := named[min(i, j)], named[max(i, j)]
+	aEnd, bEnd := bf.sectionBodyEnd(a), bf.sectionBodyEnd(b)
+
+	var block []string
+	block = append(block, bf.lines[b.Line:bEnd]...)
+	block = append(block, bf.lines[aEnd:a.End]...)
+	block = append(block, bf.lines[a.Line:aEnd]...)
+	bf.splice(a.Line, bEnd, block)
+	return true
+}
+
+// deleteSection removes s's header and everything under it. The last
+// section also takes the blank lines above it, so the file doesn't end in
+// blanks.
+func (bf *brewfile) deleteSection(s *section) {
+	from := s.Line
+	if s.End == len(bf.lines) {
+		for from > 0 && strings.TrimSpace(bf.lines[from-1]) == "" {
+			from--
+		}
+	}
+	bf.splice(from, s.End, nil)
+}
+
+// mergeSection moves s's entries, with their annotations, into the section
+// into alphabetically and then deletes s. When the two sections' "@profile"
+// tags differ, entries that relied on s's tag get it inline, so the merge
+// does not change which profiles install them; it returns how many, and the
+// profiles they were tagged with.
+func (bf *brewfile) mergeSection(s, into *section) (tagged int, retag []string) {
+	type moved struct {
+		block []string
+		name  string
+		kind  pkgKind
+	}
+	retag = sectionProfiles(s)
+	if slices.Equal(retag, sectionProfiles(into)) {
+		retag = nil
+	} else if len(retag) == 0 {
+		retag = []string{bfile.AllProfiles}
+	}
+	var entries []moved
+	for _, e := range s.Entries {
+		block := slices.Clone(bf.lines[e.DocLine : e.Line+1])
+		block[len(block)-1] = strings.TrimSpace(block[len(block)-1])
+		if retag != nil && !hasOwnProfileTag(e) {
+			tag := *e
+			tag.Comment = bfile.SetProfileTag(e.Comment, retag)
+			block[len(block)-1] = strings.TrimSpace(tag.Format())
+			tagged++
+		}
+		entries = append(entries, moved{block, e.Name, e.Kind})
+	}
+	ti := slices.Index(bf.doc.Sections, into)
+	if ti > slices.Index(bf.doc.Sections, s) {
+		ti--
+	}
+	bf.deleteSection(s)
+	target := bf.doc.Sections[ti].Name
+	for _, e := range entries {
+		bf.insertLines(e.block, e.name, e.kind, target)
+	}
+	return tagged, retag
+}
+
+// sectionProfiles is s's own "@profile" tag, sorted.
+func sectionProfiles(s *section) []string {
+	p := slices.Clone(s.Profiles)
+	slices.Sort(p)
+	return p
+}
+
+// hasOwnProfileTag reports whether e carries a "@profile" tag of its own,
+// inline or in its annotation, rather than taking its section's.
+func hasOwnProfileTag(e *entry) bool {
+	if _, ok := bfile.ProfileTag(e.Comment); ok {
+		return true
+	}
+	return slices.ContainsFunc(e.Doc, func(d string) bool {
+		_, ok := bfile.ProfileTag(d)
+		return ok
+	})
+}
+
+// ── Section UI ────────────────────────────────────────────────────────────
+
+// applySectionName finishes the new-section and rename prompts.
+func (m model) applySectionName() model {
+	name := strings.TrimSpace(m.inputBuf)
+	m.inputBuf = ""
+	m.state = stateNormal
+	sec := m.currentSection()
+	if m.secRename {
+		if sec == nil || sec.Line < 0 {
+			return m
+		}
+		old := sec.Name
+		m.checkpoint(fmt.Sprintf("rename section %s → %s", old, name))
+		m.bf.renameSection(sec, name)
+		m.dirty = true
+		m.flash = fmt.Sprintf("renamed %s → %s", old, m.bf.sections[m.secIdx].Name)
+		return m
+	}
+	m.checkpoint(fmt.Sprintf("new section %s", name))
+	m.bf.createSection(name, sec)
+	// The new section follows the one under the cursor, or ends the file.
+	if sec != nil {
+		m.secIdx++
+	} else {
+		m.secIdx = len(m.bf.sections) - 1
+	}
+	m.entIdx = 0
+	m.leftFocus = true
+	m.dirty = true
+	m.flash = "created section " + m.currentSection().Name
+	return m
+}
+
+// moveCurrentSection shifts the section under the cursor up or down.
+func (m model) moveCurrentSection(delta int) model {
+	sec := m.currentSection()
+	if sec == nil || sec.Line < 0 {
+		return m
+	}
+	named := m.bf.namedSections()
+	if j := slices.Index(named, sec) + delta; j < 0 || j >= len(named) {
+		m.flash = "section is already at the " + map[int]string{-1: "top", 1: "bottom"}[delta]
+		return m
+	}
+	name := sec.Name
+	m.checkpoint("move section " + name)
+	m.bf.moveSection(sec, delta)
+	m.secIdx += delta
+	m.entIdx = 0
+	m.dirty = true
+	m.flash = fmt.Sprintf("moved section %s %s", name, map[int]string{-1: "up", 1: "down"}[delta])
+	return m
+}
+
+// removeCurrentSection starts deleting the section under the cursor: an
+// empty one is confirmed, one with entries asks where to merge them.
+func (m model) removeCurrentSection() model {
+	sec := m.currentSection()
+	switch {
+	case sec == nil:
+	case sec.Line < 0:
+		m.flash = "the entries above the first header have no section to delete"
+	case len(sec.Entries) == 0:
+		m.state = stateSectionDeleteConfirm
+	default:
+		m.moveSecIdx = 0
+		if m.secIdx == 0 && len(m.bf.sections) > 1 {
+			m.moveSecIdx = 1
+		}
+		m.state = stateSectionMerge
+	}
+	return m
+}
+
+func (m model) handleSectionMerge(key string) (model, tea.Cmd) {
+	secs := m.bf.sections
+	sec := m.currentSection()
+	switch key {
+	case "esc":
+		m.state = stateNormal
+	case "up", "k":
+		if m.moveSecIdx > 0 {
+			m.moveSecIdx--
+		}
+	case "down", "j":
+		if m.moveSecIdx < len(secs)-1 {
+			m.moveSecIdx++
+		}
+	case "enter":
+		if m.moveSecIdx == m.secIdx {
+			m.flash = "pick a different section to merge into"
+			break
+		}
+		target := secs[m.moveSecIdx]
+		m.checkpoint(fmt.Sprintf("merge section %s into %s", sec.Name, target.Name))
+		name, n := sec.Name, len(sec.Entries)
+		tagged, profiles := m.bf.mergeSection(sec, target)
+		m.dirty = true
+		m.flash = fmt.Sprintf("merged %d entries from %s into %s", n, name, target.Name)
+		if tagged > 0 {
+			m.flash += fmt.Sprintf(", tagged %d @profile %s", tagged, strings.Join(profiles, " "))
+		}
+		m.state = stateNormal
+		m.clampCursor()
+	case "D":
+		m.checkpoint(fmt.Sprintf("delete section %s", sec.Name))
+		name, n := sec.Name, len(sec.Entries)
+		m.bf.deleteSection(sec)
+		m.dirty = true
+		m.flash = fmt.Sprintf("deleted section %s and its %d entries", name, n)
+		m.state = stateNormal
+		m.clampCursor()
+	}
+	return m, nil
+}
+
+func (m model) handleSectionDeleteConfirm(key string) (model, tea.Cmd) {
+	m.state = stateNormal
+	sec := m.currentSection()
+	if (key == "y" || key == "enter") && sec != nil {
+		m.checkpoint(fmt.Sprintf("delete section %s", sec.Name))
+		name := sec.Name
+		m.bf.deleteSection(sec)
+		m.dirty = true
+		m.flash = "deleted section " + name
+		m.clampCursor()
+		return m, nil
+	}
+	m.flash = "cancelled"
+	return m, nil
+}
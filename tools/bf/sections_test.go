@@ -0,0 +1,150 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func fixtureText(m model) string {
+	return strings.Join(m.bf.lines, "\n") + "\n"
+}
+
+func TestSectionCreateRenameMove(t *testing.T) {
+	m := fixtureModel(t)
+
+	// New section after Media, typed in the left pane.
+	m = press(m, "j", "n", "Audio")
+	m = press(m, "enter")
+	if sec := m.currentSection(); sec == nil || sec.Name != "Audio" || !m.leftFocus {
+		t.Fatalf("new section not selected: %+v", sec)
+	}
+
+	// Entries can be added to the still-empty section.
+	m.bf.addEntry("sox", kindBrew, false, "Audio")
+	m.bf.reload()
+
+	m = press(m, "r")
+	if m.inputBuf != "Audio" {
+		t.Errorf("rename prompt = %q", m.inputBuf)
+	}
+	m.inputBuf = "Sound"
+	m = press(m, "enter")
+
+	// Media is "CLI Tools - Media"; renaming keeps the prefix.
+	m = press(m, "k", "r")
+	m.inputBuf = "Video"
+	m = press(m, "enter")
+
+	m = press(m, "K")
+	if m.currentSection().Name != "Video" || m.secIdx != 0 {
+		t.Errorf("after K: cursor on %q at %d", m.currentSection().Name, m.secIdx)
+	}
+	if m = press(m, "K"); m.flash != "section is already at the top" {
+		t.Errorf("flash = %q", m.flash)
+	}
+
+	want := `## CLI Tools - Video
+brew "ffmpeg"
+brew "yt-dlp"
+
+## Taps
+tap "sevmorris/tap"
+
+## Sound
+brew "sox"
+
+## Casks - General Applications & Utilities
+cask "firefox", greedy: true
+cask "vlc"
+`
+	if got := fixtureText(m); got != want {
+		t.Errorf("file =\n%s\nwant\n%s", got, want)
+	}
+
+	// Moving the last section up keeps every blank separator.
+	m = press(m, "j", "j", "j", "K")
+	if got := fixtureText(m); !strings.HasSuffix(got, "## Casks - General Applications & Utilities\ncask \"firefox\", greedy: true\ncask \"vlc\"\n\n## Sound\nbrew \"sox\"\n") {
+		t.Errorf("after moving last section up:\n%s", got)
+	}
+}
+
+func TestSectionMergeAndDelete(t *testing.T) {
+	m := fixtureModel(t)
+	m.bf.lines = strings.Split(strings.TrimSuffix(`## Taps
+tap "sevmorris/tap"
+
+## CLI Tools - Media
+brew "yt-dlp"
+# Encoder note
+brew "aaa"
+
+## Empty
+
+## Casks - General Applications & Utilities
+cask "firefox", greedy: true
+cask "vlc"
+`, "\n"), "\n")
+	m.bf.reload()
+
+	// Merge Media into Taps: entries move with their annotation.
+	m = press(m, "j", "d")
+	if m.state != stateSectionMerge {
+		t.Fatalf("d on a section with entries: state = %v", m.state)
+	}
+	m = press(m, "k", "enter")
+	want := `## Taps
+tap "sevmorris/tap"
+# Encoder note
+brew "aaa"
+brew "yt-dlp"
+
+## Empty
+
+## Casks - General Applications & Utilities
+cask "firefox", greedy: true
+cask "vlc"
+`
+	if got := fixtureText(m); got != want {
+		t.Errorf("after merge:\n%s\nwant\n%s", got, want)
+	}
+
+	// An empty section is just confirmed away.
+	if m.currentSection().Name != "Empty" {
+		t.Fatalf("cursor on %q", m.currentSection().Name)
+	}
+	m = press(m, "d", "y")
+	if m.bf.doc.Section("Empty") != nil || strings.Contains(fixtureText(m), "\n\n\n") {
+		t.Errorf("after delete:\n%s", fixtureText(m))
+	}
+
+	// Deleting the last section with D drops its entries and the blank above.
+	m = press(m, "d", "D")
+	if got := fixtureText(m); strings.Contains(got, "vlc") || strings.HasSuffix(got, "\n\n") {
+		t.Errorf("after D:\n%q", got)
+	}
+	if m = press(m, "u"); m.bf.doc.Find(kindCask, "vlc") == nil {
+		t.Error("undo did not restore the deleted section")
+	}
+
+	// Merging a studio-only section into an untagged one keeps its entries
+	// studio-only; entries with their own tag keep that.
+	m = fixtureModelOf(t, profileFixture)
+	m = press(m, "j", "d", "k", "enter")
+	profiles := map[string][]string{"ffmpeg": nil, "sox": {"studio"}, "ilok-license-manager": {"studio"}, "spotify": {"all"}}
+	for _, e := range m.bf.doc.Entries() {
+		if !slices.Equal(e.Profiles, profiles[e.Name]) || m.bf.sectionOf(e).Name != "Media" {
+			t.Errorf("after tagged merge: %s in %s, profiles %q", e.Name, m.bf.sectionOf(e).Name, e.Profiles)
+		}
+	}
+	if m.flash != "merged 2 entries from Audio into Media, tagged 1 @profile studio" {
+		t.Errorf("flash = %q", m.flash)
+	}
+
+	// The other way, moved entries are tagged for every profile.
+	m = fixtureModelOf(t, profileFixture)
+	m = press(m, "d", "j", "enter")
+	if e := m.bf.doc.Find(kindBrew, "ffmpeg"); e == nil || !slices.Equal(e.Profiles, []string{"all"}) {
+		t.Errorf("untagged entry merged into a studio section:\n%s", fixtureText(m))
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ── Installed state ───────────────────────────────────────────────────────
+
+// pkgState is what Homebrew reports about one installed formula or cask.
+type pkgState struct {
+	Version string // installed version
+	Latest  string // newer version when outdated
+	Pinned  bool
+}
+
+func (s pkgState) outdated() bool { return s.Latest != "" }
+
+// installedState is a snapshot of what is installed on this machine.
+type installedState struct {
+	Formulae map[string]pkgState
+	Casks    map[string]pkgState
+	Taps     map[string]bool
+}
+
+// of reports e's state. checked is false for kinds bf cannot cheaply check
+// (mas, vscode, whalebrew); installed is false when e is missing.
+func (s *installedState) of(e *entry) (st pkgState, installed, checked bool) {
+	switch e.Kind {
+	case kindBrew:
+		// Tap formulae are listed by short name.
+		name := e.Name[strings.LastIndex(e.Name, "/")+1:]
+		st, installed = s.Formulae[name]
+		return st, installed, true
+	case kindCask:
+		name := e.Name[strings.LastIndex(e.Name, "/")+1:]
+		st, installed = s.Casks[name]
+		return st, installed, true
+	case kindTap:
+		return pkgState{}, s.Taps[e.Name], true
+	}
+	return pkgState{}, false, false
+}
+
+func (b *execBrew) Installed() (*installedState, error) {
+	if _, err := exec.LookPath(b.bin); err != nil {
+		return nil, fmt.Errorf("%s not found", b.bin)
+	}
+	s := &installedState{
+		Formulae: map[string]pkgState{},
+		Casks:    map[string]pkgState{},
+		Taps:     map[string]bool{},
+	}
+	for _, kind := range []string{"formula", "cask"} {
+		out, err := exec.Command(b.bin, "list", "--"+kind, "--versions").Output()
+		if err != nil {
+			return nil, fmt.Errorf("brew list --%s: %w", kind, err)
+		}
+		into := s.Formulae
+		if kind == "cask" {
+			into = s.Casks
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			f := strings.Fields(line)
+			if len(f) == 0 {
+				continue
+			}
+			st := pkgState{}
+			if len(f) > 1 {
+				st.Version = f[len(f)-1]
+			}
+			into[f[0]] = st
+		}
+	}
+	if out, err := exec.Command(b.bin, "tap").Output(); err == nil {
+		for _, t := range strings.Fields(string(out)) {
+			s.Taps[t] = true
+		}
+	}
+	// brew outdated exits 1 when something is outdated; the JSON is still
+	// on stdout.
+	out, _ := exec.Command(b.bin, "outdated", "--json=v2").Output()
+	if err := applyOutdated(s, out); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (b *execBrew) Leaves() ([]string, error) {
+	out, err := exec.Command(b.bin, "leaves", "--installed-on-request").Output()
+	if err != nil {
+		return nil, fmt.Errorf("brew leaves: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+func (b *execBrew) Deps() (map[string][]string, error) {
+	out, err := exec.Command(b.bin, "deps", "--installed", "--formula").Output()
+	if err != nil {
+		return nil, fmt.Errorf("brew deps: %w", err)
+	}
+	deps := parseDeps(out)
+	// Casks can depend on formulae too; older brews have no --cask here.
+	if out, err := exec.Command(b.bin, "deps", "--installed", "--cask").Output(); err == nil {
+		for k, v := range parseDeps(out) {
+			deps[k] = v
+		}
+	}
+	return deps, nil
+}
+
+// parseDeps reads `brew deps --installed` lines of the form "name: dep dep".
+// Tap formulae are keyed by short name, as brew list reports them.
+func parseDeps(out []byte) map[string][]string {
+	deps := map[string][]string{}
+	short := func(n string) string { return n[strings.LastIndex(n, "/")+1:] }
+	for _, line := range strings.Split(string(out), "\n") {
+		name, rest, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			continue
+		}
+		var ds []string
+		for _, d := range strings.Fields(rest) {
+			ds = append(ds, short(d))
+		}
+		deps[short(strings.TrimSpace(name))] = ds
+	}
+	return deps
+}
+
+// applyOutdated merges `brew outdated --json=v2` output into s.
+func applyOutdated(s *installedState, data []byte) error {
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil
+	}
+	type item struct {
+		Name              string   `json:"name"`
+		InstalledVersions []string `json:"installed_versions"`
+		CurrentVersion    string   `json:"current_version"`
+		Pinned            bool     `json:"pinned"`
+	}
+	var raw struct {
+		Formulae []item `json:"formulae"`
+		Casks    []item `json:"casks"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("brew outdated: %w", err)
+	}
+	apply := func(into map[string]pkgState, items []item) {
+		for _, it := range items {
+			st := into[it.Name]
+			if n := len(it.InstalledVersions); n > 0 {
+				st.Version = it.InstalledVersions[n-1]
+			}
+			st.Latest = it.CurrentVersion
+			st.Pinned = it.Pinned
+			into[it.Name] = st
+		}
+	}
+	apply(s.Formulae, raw.Formulae)
+	apply(s.Casks, raw.Casks)
+	return nil
+}
+
+type statusMsg struct {
+	state *installedState
+	err   error
+}
+
+// fetchStatus asks the backend for installed and outdated state in the
+// background; the badges fill in when it arrives.
+func fetchStatus(b brewBackend) tea.Cmd {
+	return func() tea.Msg {
+		st, err := b.Installed()
+		return statusMsg{state: st, err: err}
+	}
+}
+
+// needsAttention reports whether e is missing or outdated, which is what the
+// filter toggle keeps.
+func (m model) needsAttention(e *entry) bool {
+	if m.status == nil {
+		return true
+	}
+	st, installed, checked := m.status.of(e)
+	return checked && (!installed || st.outdated())
+}
+
+// entries returns the section's entries as shown in the package pane: those
+// in the active profile, with the missing/outdated filter once state has
+// loaded.
+func (m model) entries(sec *section) []*entry {
+	if sec == nil {
+		return nil
+	}
+	attention := m.filterAttention && m.status != nil
+	if !attention && m.allProfiles {
+		return sec.Entries
+	}
+	var out []*entry
+	for _, e := range sec.Entries {
+		if m.inProfile(e) && (!attention || m.needsAttention(e)) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// statusBadge renders the one-glyph installed column for e.
+func (m model) statusBadge(e *entry) string {
+	if m.status == nil {
+		return "  "
+	}
+	st, installed, checked := m.status.of(e)
+	switch {
+	case !checked:
+		return "  "
+	case !installed:
+		return styleMissing.Render("✗ ")
+	case st.outdated():
+		return styleOutdated.Render("↑ ")
+	default:
+		return styleInstalled.Render("✓ ")
+	}
+}
+
+// statusDetail describes e's version state for the detail column: the
+// upgrade for an outdated entry, and a pin.
+func (m model) statusDetail(e *entry) string {
+	if m.status == nil {
+		return ""
+	}
+	st, installed, _ := m.status.of(e)
+	if !installed {
+		return ""
+	}
+	var parts []string
+	if st.outdated() {
+		parts = append(parts, styleOutdated.Render(st.Version+" → "+st.Latest))
+	}
+	if locked := m.bf.lockedVersion(e); locked != "" && locked != st.Version {
+		parts = append(parts, styleOutdated.Render(st.Version+" ≠ lock "+locked))
+	}
+	if st.Pinned {
+		parts = append(parts, styleOpts.Render("pinned"))
+	}
+	return strings.Join(parts, " ")
+}
+
+// jumpTo puts the cursor on e in the package pane, dropping the filters if
+// they hide e.
+func (m model) jumpTo(e *entry) model {
+	for si, s := range m.bf.sections {
+		if !slices.Contains(s.Entries, e) {
+			continue
+		}
+		if !slices.Contains(m.entries(s), e) {
+			m.filterAttention = false
+			m.allProfiles = m.allProfiles || !e.InProfile(m.profile)
+		}
+		m.secIdx, m.entIdx = si, slices.Index(m.entries(s), e)
+		m.leftFocus = false
+	}
+	return m
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyOutdated(t *testing.T) {
+	s := &installedState{
+		Formulae: map[string]pkgState{"ffmpeg": {Version: "7.0"}},
+		Casks:    map[string]pkgState{"vlc": {Version: "3.0.20"}},
+	}
+	data := `{"formulae":[{"name":"ffmpeg","installed_versions":["7.0"],"current_version":"7.1","pinned":true}],
+		"casks":[{"name":"vlc","installed_versions":["3.0.20"],"current_version":"3.0.21"}]}`
+	if err := applyOutdated(s, []byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	want := &installedState{
+		Formulae: map[string]pkgState{"ffmpeg": {Version: "7.0", Latest: "7.1", Pinned: true}},
+		Casks:    map[string]pkgState{"vlc": {Version: "3.0.20", Latest: "3.0.21"}},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("state = %+v", s)
+	}
+	if err := applyOutdated(s, nil); err != nil {
+		t.Errorf("empty output: %v", err)
+	}
+}
+
+func TestStatusBadgesAndFilter(t *testing.T) {
+	m := fixtureModel(t)
+	m.brew = &fakeBrew{state: &installedState{
+		Formulae: map[string]pkgState{"ffmpeg": {Version: "7.0", Latest: "7.1"}},
+		Casks:    map[string]pkgState{"firefox": {Version: "131"}, "vlc": {Version: "3", Pinned: true}},
+		Taps:     map[string]bool{"sevmorris/tap": true},
+	}}
+	if m = press(m, "f"); m.filterAttention {
+		t.Fatal("filter turned on before state loaded")
+	}
+	next, _ := m.Update(fetchStatus(m.brew)())
+	m = next.(model)
+
+	m = press(m, "j", "l") // Media: ffmpeg (outdated), yt-dlp (missing)
+	m.width, m.height = 100, 12
+	view := m.View()
+	for _, want := range []string{"↑ ffmpeg", "7.0 → 7.1", "✗ yt-dlp"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view lacks %q:\n%s", want, view)
+		}
+	}
+
+	m = press(m, "j", "f")
+	if e := m.currentEntry(); !m.filterAttention || e == nil || e.Name != "ffmpeg" {
+		t.Fatalf("filter on: cursor = %v", e)
+	}
+	m = press(m, "h", "j")
+	if n := len(m.entries(m.currentSection())); n != 0 {
+		t.Errorf("Casks should have nothing missing or outdated, got %d", n)
+	}
+
+	// Jumping to a filtered-out entry drops the filter.
+	m = m.jumpTo(m.bf.doc.Find(kindCask, "vlc"))
+	if e := m.currentEntry(); m.filterAttention || e == nil || e.Name != "vlc" {
+		t.Errorf("jumpTo hidden entry: filter=%v cursor=%v", m.filterAttention, e)
+	}
+}
+
+func TestParseDeps(t *testing.T) {
+	out := "ffmpeg: libpng x264\njq: oniguruma\nsevmorris/tap/mrk-tool: jq\nzlib:\n"
+	got := parseDeps([]byte(out))
+	want := map[string][]string{
+		"ffmpeg":   {"libpng", "x264"},
+		"jq":       {"oniguruma"},
+		"mrk-tool": {"jq"},
+		"zlib":     nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDeps = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,640 @@
+// Package brewfile parses and writes Homebrew Bundle Brewfiles for the mrk tools.
+//
+// The parser is lossless: a File keeps every source line verbatim and layers
+// a structural view (taps, sections, entries, annotations) on top of them, so
+// Bytes reproduces the input byte-for-byte until a line is edited.
+package brewfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ── Kinds ────────────────────────────────────────────────────────────────────
+
+// Kind is the Brewfile directive an entry was declared with.
+type Kind int
+
+const (
+	KindTap Kind = iota
+	KindBrew
+	KindCask
+	KindMas
+	KindVSCode
+	KindWhalebrew
+)
+
+var kindNames = [...]string{"tap", "brew", "cask", "mas", "vscode", "whalebrew"}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Kinds lists every directive the parser understands, in display order.
+func Kinds() []Kind {
+	return []Kind{KindTap, KindBrew, KindCask, KindMas, KindVSCode, KindWhalebrew}
+}
+
+// ParseKind maps a directive keyword ("brew", "cask", …) to its Kind.
+func ParseKind(s string) (Kind, bool) {
+	for i, n := range kindNames {
+		if n == s {
+			return Kind(i), true
+		}
+	}
+	return 0, false
+}
+
+// ── AST ──────────────────────────────────────────────────────────────────────
+
+// Option is one `key: value` pair from an entry's option hash. Value is kept
+// as raw Ruby source (`true`, `["--with-x"]`, `:none`, `"foo"`).
+type Option struct {
+	Key   string
+	Value string
+}
+
+// Entry is a single tap/brew/cask/mas/vscode/whalebrew directive.
+type Entry struct {
+	Kind    Kind
+	Name    string
+	Args    []string // extra positional arguments (raw), e.g. a tap's clone URL
+	Options []Option
+	Comment string   // trailing inline comment, without the leading "#"
+	Doc     []string // annotation lines directly above the entry, without "#"
+
+	// Profiles the entry is limited to, from its own "@profile" tag or its
+	// section's; nil means every profile.
+	Profiles []string
+
+	Line    int // index of the directive in File.Lines
+	DocLine int // index of the first annotation line; == Line when Doc is empty
+
+	indent     string
+	commentPad string
+	eol        string
+}
+
+// Section is a run of lines introduced by a "## " header.
+type Section struct {
+	Name    string // short display name, unique within the file
+	Header  string // full header text after "##"
+	Line    int    // header line index; -1 for the implicit leading section
+	End     int    // index one past the section's last line
+	Entries []*Entry
+
+	Profiles []string // from "@profile" tags not attached to an entry
+}
+
+// File is a parsed Brewfile. Lines is authoritative; everything else is
+// derived from it by Reparse.
+type File struct {
+	Lines        []string
+	FinalNewline bool
+	Sections     []*Section
+	Taps         []*Entry
+}
+
+// ── Loading and writing ──────────────────────────────────────────────────────
+
+// Parse builds a File from raw Brewfile bytes.
+func Parse(data []byte) *File {
+	f := &File{FinalNewline: bytes.HasSuffix(data, []byte("\n"))}
+	if len(data) > 0 {
+		s := string(data)
+		if f.FinalNewline {
+			s = s[:len(s)-1]
+		}
+		f.Lines = strings.Split(s, "\n")
+	}
+	f.Reparse()
+	return f
+}
+
+// ParseLines builds a File from lines that are already split. The result is
+// written with a trailing newline.
+func ParseLines(lines []string) *File {
+	f := &File{Lines: lines, FinalNewline: true}
+	f.Reparse()
+	return f
+}
+
+// Load reads and parses the Brewfile at path.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data), nil
+}
+
+// Bytes renders the file exactly as it will be written to disk.
+func (f *File) Bytes() []byte {
+	out := strings.Join(f.Lines, "\n")
+	if f.FinalNewline && len(f.Lines) > 0 {
+		out += "\n"
+	}
+	return []byte(out)
+}
+
+// WriteFile writes the file to path atomically (temp file + rename in the
+// same directory), keeping the existing file's permissions.
+func (f *File) WriteFile(path string) error {
+	return writeAtomic(path, f.Bytes())
+}
+
+// writeAtomic replaces path with data through a temp file in the same
+// directory, keeping the existing file's permissions.
+func writeAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".bf-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ── Structure ────────────────────────────────────────────────────────────────
+
+// Reparse rebuilds Sections and Taps from Lines. Call it after editing Lines.
+func (f *File) Reparse() {
+	f.Sections, f.Taps = nil, nil
+	var cur *Section
+	var lead []string // section tags seen before the implicit leading section exists
+	docStart := -1
+
+	// loose collects the profile tags of a comment block that ended without
+	// reaching an entry; they apply to the whole section.
+	loose := func(end int) {
+		if docStart < 0 {
+			return
+		}
+		for _, d := range f.Lines[docStart:end] {
+			if p, ok := ProfileTag(commentText(d)); ok {
+				if cur != nil {
+					cur.Profiles = append(cur.Profiles, p...)
+				} else {
+					lead = append(lead, p...)
+				}
+			}
+		}
+		docStart = -1
+	}
+
+	for i, raw := range f.Lines {
+		trimmed := strings.TrimSpace(raw)
+
+		if text, ok := headerText(trimmed); ok {
+			loose(i)
+			if cur != nil {
+				cur.End = i
+			}
+			cur = &Section{Header: text, Line: i}
+			f.Sections = append(f.Sections, cur)
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "#") {
+			if docStart < 0 {
+				docStart = i
+			}
+			continue
+		}
+
+		e, ok := ParseEntry(raw)
+		if !ok {
+			// Blank lines and unrecognised Ruby break an annotation block.
+			loose(i)
+			continue
+		}
+		e.Line, e.DocLine = i, i
+		if docStart >= 0 {
+			e.DocLine = docStart
+			for _, d := range f.Lines[docStart:i] {
+				e.Doc = append(e.Doc, commentText(d))
+			}
+		}
+		docStart = -1
+
+		if cur == nil {
+			cur = &Section{Name: "General", Header: "General", Line: -1, Profiles: lead}
+			f.Sections = append(f.Sections, cur)
+		}
+		cur.Entries = append(cur.Entries, e)
+		if e.Kind == KindTap {
+			f.Taps = append(f.Taps, e)
+		}
+	}
+	loose(len(f.Lines))
+	if cur != nil {
+		cur.End = len(f.Lines)
+	}
+	f.nameSections()
+	f.resolveProfiles()
+}
+
+// nameSections assigns each header its short name, falling back to the full
+// header text when two headers would otherwise share a name.
+func (f *File) nameSections() {
+	seen := map[string]bool{}
+	for _, s := range f.Sections {
+		if s.Line < 0 {
+			seen[s.Name] = true
+			continue
+		}
+		name := SectionName(s.Header)
+		if seen[name] {
+			name = s.Header
+		}
+		base := name
+		for n := 2; seen[name]; n++ {
+			name = base + " (" + strconv.Itoa(n) + ")"
+		}
+		s.Name = name
+		seen[name] = true
+	}
+}
+
+// Entries returns every entry in file order, taps included.
+func (f *File) Entries() []*Entry {
+	var out []*Entry
+	for _, s := range f.Sections {
+		out = append(out, s.Entries...)
+	}
+	return out
+}
+
+// Find returns the first entry with the given kind and name, or nil.
+func (f *File) Find(kind Kind, name string) *Entry {
+	for _, s := range f.Sections {
+		for _, e := range s.Entries {
+			if e.Kind == kind && e.Name == name {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// Section returns the section with the given short name, or nil.
+func (f *File) Section(name string) *Section {
+	for _, s := range f.Sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// SectionOf returns the section that contains e, or nil.
+func (f *File) SectionOf(e *Entry) *Section {
+	for _, s := range f.Sections {
+		for _, x := range s.Entries {
+			if x == e {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// SectionName derives the short display name for a "## " header:
+//
+//	"CLI Tools - General Utilities & Power User Tools" → "CLI Tools"
+//	"CLI Tools - Media"                               → "Media"
+//	"Languages / runtimes"                            → "Languages"
+//
+// A " - " (or " — ") suffix of at most two words is specific enough to stand
+// alone; a longer one is a description and the prefix is used instead.
+func SectionName(header string) string {
+	name := strings.TrimSpace(header)
+	for _, sep := range []string{" - ", " — "} {
+		if idx := strings.LastIndex(name, sep); idx != -1 {
+			suffix := name[idx+len(sep):]
+			if len(strings.Fields(suffix)) <= 2 {
+				name = suffix
+			} else {
+				name = name[:idx]
+			}
+			break
+		}
+	}
+	for _, sep := range []string{" / ", " & "} {
+		if idx := strings.Index(name, sep); idx != -1 {
+			name = name[:idx]
+		}
+	}
+	return strings.TrimSpace(name)
+}
+
+// RenameHeader replaces the part of header that SectionName shows with
+// name, keeping the rest, so renaming "CLI Tools - Media" to "Video" gives
+// "CLI Tools - Video" and renaming "CLI Tools - General Utilities & Power
+// User Tools" to "Shell" keeps the long description. A name that already
+// contains a " - " separator is taken as the whole new header.
+func RenameHeader(header, name string) string {
+	name = strings.TrimSpace(name)
+	header = strings.TrimSpace(header)
+	for _, sep := range []string{" - ", " — "} {
+		if strings.Contains(name, sep) {
+			return name
+		}
+	}
+	for _, sep := range []string{" - ", " — "} {
+		if idx := strings.LastIndex(header, sep); idx != -1 {
+			prefix, suffix := header[:idx], header[idx+len(sep):]
+			if len(strings.Fields(suffix)) <= 2 {
+				return prefix + sep + name
+			}
+			return renameLead(prefix, name) + sep + suffix
+		}
+	}
+	return renameLead(header, name)
+}
+
+// renameLead replaces the text before a " / " or " & " qualifier.
+func renameLead(s, name string) string {
+	cut := len(s)
+	for _, sep := range []string{" / ", " & "} {
+		if idx := strings.Index(s, sep); idx != -1 && idx < cut {
+			cut = idx
+		}
+	}
+	return name + s[cut:]
+}
+
+func headerText(trimmed string) (string, bool) {
+	if !strings.HasPrefix(trimmed, "##") {
+		return "", false
+	}
+	text := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+	return text, text != ""
+}
+
+func commentText(line string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+}
+
+// ── Entries ──────────────────────────────────────────────────────────────────
+
+var (
+	reKeyword   = regexp.MustCompile(`^[a-z_]+`)
+	reSymbolKey = regexp.MustCompile(`^([A-Za-z_]\w*[?!]?):\s*(.*)$`)
+	reRocketKey = regexp.MustCompile(`^(?::([A-Za-z_]\w*)|"([^"]+)")\s*=>\s*(.*)$`)
+	reBareKey   = regexp.MustCompile(`^[A-Za-z_]\w*[?!]?$`)
+)
+
+// ParseEntry parses a single directive line such as
+//
+//	cask "firefox", greedy: true  # comment
+//
+// It reports false for blank lines, comments and any Ruby it does not model.
+func ParseEntry(line string) (*Entry, bool) {
+	body, eol := line, ""
+	if strings.HasSuffix(body, "\r") {
+		body, eol = body[:len(body)-1], "\r"
+	}
+	rest := strings.TrimLeft(body, " \t")
+	indent := body[:len(body)-len(rest)]
+
+	word := reKeyword.FindString(rest)
+	kind, ok := ParseKind(word)
+	if !ok {
+		return nil, false
+	}
+	rest = rest[len(word):]
+	if rest == "" || (rest[0] != ' ' && rest[0] != '\t') {
+		return nil, false
+	}
+
+	items, comment, pad, ok := splitArgs(rest)
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+	name, ok := unquote(items[0])
+	if !ok || name == "" {
+		return nil, false
+	}
+
+	e := &Entry{
+		Kind:       kind,
+		Name:       name,
+		Comment:    comment,
+		indent:     indent,
+		commentPad: pad,
+		eol:        eol,
+	}
+	for _, it := range items[1:] {
+		if k, v, ok := splitOption(it); ok {
+			e.Options = append(e.Options, Option{Key: k, Value: v})
+		} else {
+			e.Args = append(e.Args, it)
+		}
+	}
+	return e, true
+}
+
+// splitArgs splits a directive's argument list at top-level commas. It stops
+// at an unquoted "#" and returns the comment text and the whitespace before it.
+func splitArgs(s string) (items []string, comment, pad string, ok bool) {
+	var quote byte
+	escaped := false
+	depth := 0
+	start := 0
+
+	push := func(end int) {
+		if it := strings.TrimSpace(s[start:end]); it != "" {
+			items = append(items, it)
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			quote = c
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				push(i)
+				start = i + 1
+			}
+		case '#':
+			if depth == 0 {
+				before := s[start:i]
+				pad = before[len(strings.TrimRight(before, " \t")):]
+				push(i)
+				return items, strings.TrimSpace(s[i+1:]), pad, true
+			}
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return nil, "", "", false
+	}
+	push(len(s))
+	return items, "", "", true
+}
+
+func splitOption(item string) (key, value string, ok bool) {
+	if m := reSymbolKey.FindStringSubmatch(item); m != nil {
+		return m[1], strings.TrimSpace(m[2]), true
+	}
+	if m := reRocketKey.FindStringSubmatch(item); m != nil {
+		key = m[1]
+		if key == "" {
+			key = m[2]
+		}
+		return key, strings.TrimSpace(m[3]), true
+	}
+	return "", "", false
+}
+
+func unquote(s string) (string, bool) {
+	if len(s) < 2 || (s[0] != '"' && s[0] != '\'') || s[len(s)-1] != s[0] {
+		return "", false
+	}
+	inner := s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) {
+			i++
+		}
+		b.WriteByte(inner[i])
+	}
+	return b.String(), true
+}
+
+// Quote renders s as a double-quoted Ruby string literal.
+func Quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// NewEntry returns an unattached entry ready to be formatted and inserted.
+func NewEntry(kind Kind, name string, opts ...Option) *Entry {
+	return &Entry{Kind: kind, Name: name, Options: opts, Line: -1, DocLine: -1}
+}
+
+// ParseOption parses a single "key: value" (or ":key => value") item as it
+// would appear in an entry's option hash.
+func ParseOption(s string) (Option, bool) {
+	items, comment, _, ok := splitArgs(" " + s)
+	if !ok || len(items) != 1 || comment != "" {
+		return Option{}, false
+	}
+	k, v, ok := splitOption(items[0])
+	if !ok || v == "" {
+		return Option{}, false
+	}
+	return Option{Key: k, Value: v}, true
+}
+
+// String renders the option as `key: value`, or as `"key" => value` when the
+// key is not a bare identifier and so only parsed from the rocket form.
+func (o Option) String() string {
+	if !reBareKey.MatchString(o.Key) {
+		return Quote(o.Key) + " => " + o.Value
+	}
+	return o.Key + ": " + o.Value
+}
+
+// Option returns the raw value of the named option.
+func (e *Entry) Option(key string) (string, bool) {
+	for _, o := range e.Options {
+		if o.Key == key {
+			return o.Value, true
+		}
+	}
+	return "", false
+}
+
+// SetOption replaces the named option in place, or appends it.
+func (e *Entry) SetOption(key, value string) {
+	for i, o := range e.Options {
+		if o.Key == key {
+			e.Options[i].Value = value
+			return
+		}
+	}
+	e.Options = append(e.Options, Option{Key: key, Value: value})
+}
+
+// DeleteOption removes the named option and reports whether it was present.
+func (e *Entry) DeleteOption(key string) bool {
+	for i, o := range e.Options {
+		if o.Key == key {
+			e.Options = append(e.Options[:i:i], e.Options[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Greedy reports whether the entry carries `greedy: true`.
+func (e *Entry) Greedy() bool {
+	v, ok := e.Option("greedy")
+	return ok && v == "true"
+}
+
+// Format renders the entry as a single Brewfile line, keeping the original
+// indentation and inline comment.
+func (e *Entry) Format() string {
+	var b strings.Builder
+	b.WriteString(e.indent)
+	b.WriteString(e.Kind.String())
+	b.WriteString(" ")
+	b.WriteString(Quote(e.Name))
+	for _, a := range e.Args {
+		b.WriteString(", " + a)
+	}
+	for _, o := range e.Options {
+		b.WriteString(", " + o.String())
+	}
+	if e.Comment != "" {
+		pad := e.commentPad
+		if pad == "" {
+			pad = "  "
+		}
+		b.WriteString(pad + "# " + e.Comment)
+	}
+	b.WriteString(e.eol)
+	return b.String()
+}
@@ -0,0 +1,213 @@
+package brewfile
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoundTripIsByteForByte(t *testing.T) {
+	cases := map[string]string{
+		"empty":            "",
+		"single newline":   "\n",
+		"no final newline": "brew \"git\"",
+		"crlf":             "## Tools\r\nbrew \"git\"\r\n",
+		"blank runs":       "\n\n## A\n\n\nbrew \"a\"\n\n",
+		"odd ruby":         "cask_args appdir: \"/Applications\"\nif OS.mac?\n  brew \"x\"\nend\n",
+		"unterminated":     "brew \"oops\n",
+	}
+	if data, err := os.ReadFile("../../Brewfile"); err == nil {
+		cases["repo Brewfile"] = string(data)
+	}
+	for name, in := range cases {
+		t.Run(name, func(t *testing.T) {
+			if out := string(Parse([]byte(in)).Bytes()); out != in {
+				t.Errorf("round trip mismatch\n--- in ---\n%q\n--- out ---\n%q", in, out)
+			}
+		})
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	cases := []struct {
+		line    string
+		kind    Kind
+		name    string
+		args    []string
+		opts    []Option
+		comment string
+	}{
+		{`brew "git"`, KindBrew, "git", nil, nil, ""},
+		{`cask "firefox", greedy: true`, KindCask, "firefox", nil,
+			[]Option{{"greedy", "true"}}, ""},
+		{`brew "chromaprint"    # Audio fingerprinting`, KindBrew, "chromaprint", nil, nil,
+			"Audio fingerprinting"},
+		{`tap "sevmorris/tap"`, KindTap, "sevmorris/tap", nil, nil, ""},
+		{`tap "user/repo", "https://example.com/repo.git"`, KindTap, "user/repo",
+			[]string{`"https://example.com/repo.git"`}, nil, ""},
+		{`mas "Xcode", id: 497799835`, KindMas, "Xcode", nil,
+			[]Option{{"id", "497799835"}}, ""},
+		{`vscode "golang.go"`, KindVSCode, "golang.go", nil, nil, ""},
+		{`whalebrew "whalebrew/wget"`, KindWhalebrew, "whalebrew/wget", nil, nil, ""},
+		{`brew "mysql", restart_service: :changed, link: false`, KindBrew, "mysql", nil,
+			[]Option{{"restart_service", ":changed"}, {"link", "false"}}, ""},
+		{`brew "x", args: ["with-a", "with-b"], conflicts_with: ["y"] # pinned`, KindBrew, "x", nil,
+			[]Option{{"args", `["with-a", "with-b"]`}, {"conflicts_with", `["y"]`}}, "pinned"},
+		{`brew "x", :link => true`, KindBrew, "x", nil, []Option{{"link", "true"}}, ""},
+		{`cask "a#b"`, KindCask, "a#b", nil, nil, ""},
+	}
+	for _, tc := range cases {
+		e, ok := ParseEntry(tc.line)
+		if !ok {
+			t.Errorf("ParseEntry(%q) failed", tc.line)
+			continue
+		}
+		if e.Kind != tc.kind || e.Name != tc.name || e.Comment != tc.comment ||
+			!reflect.DeepEqual(e.Args, tc.args) || !reflect.DeepEqual(e.Options, tc.opts) {
+			t.Errorf("ParseEntry(%q) = %s %q args=%q opts=%v comment=%q",
+				tc.line, e.Kind, e.Name, e.Args, e.Options, e.Comment)
+		}
+		if got := e.Format(); got != tc.line && tc.line != `brew "x", :link => true` {
+			t.Errorf("Format() = %q, want %q", got, tc.line)
+		}
+	}
+
+	for _, line := range []string{"", "# brew \"x\"", "brewery \"x\"", "brew x", `brew "x`, "cask_args appdir: \"/A\""} {
+		if _, ok := ParseEntry(line); ok {
+			t.Errorf("ParseEntry(%q) unexpectedly succeeded", line)
+		}
+	}
+}
+
+func TestOptionEditingKeepsComment(t *testing.T) {
+	e, _ := ParseEntry(`  cask "vlc"   # player`)
+	e.SetOption("greedy", "true")
+	if got, want := e.Format(), `  cask "vlc", greedy: true   # player`; got != want {
+		t.Errorf("after SetOption: %q, want %q", got, want)
+	}
+	if !e.Greedy() {
+		t.Error("Greedy() = false after SetOption")
+	}
+	e.DeleteOption("greedy")
+	if got, want := e.Format(), `  cask "vlc"   # player`; got != want {
+		t.Errorf("after DeleteOption: %q, want %q", got, want)
+	}
+}
+
+func TestOptionFormatRoundTrips(t *testing.T) {
+	for _, line := range []string{
+		`brew "x", link: false`,
+		`brew "x", "foo-bar" => 1`,
+		`cask "y", "with space" => "z", greedy: true`,
+	} {
+		e, ok := ParseEntry(line)
+		if !ok {
+			t.Fatalf("ParseEntry(%q) failed", line)
+		}
+		got := e.Format()
+		if got != line {
+			t.Errorf("Format() = %q, want %q", got, line)
+		}
+		again, ok := ParseEntry(got)
+		if !ok || !reflect.DeepEqual(again.Options, e.Options) {
+			t.Errorf("re-parsing %q: options %v, want %v", got, again.Options, e.Options)
+		}
+	}
+}
+
+func TestSectionsAndAnnotations(t *testing.T) {
+	src := `brew "early"
+## Taps
+# Required for doublender below.
+tap "sevmorris/tap"
+
+## CLI Tools - General Utilities & Power User Tools
+brew "bash"
+# GNU coreutils note
+brew "coreutils"
+
+## CLI Tools - Media
+brew "ffmpeg"
+
+## Languages / runtimes
+## Empty Section
+`
+	f := Parse([]byte(src))
+
+	var names []string
+	for _, s := range f.Sections {
+		names = append(names, s.Name)
+	}
+	want := []string{"General", "Taps", "CLI Tools", "Media", "Languages", "Empty Section"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("section names = %q, want %q", names, want)
+	}
+
+	if len(f.Taps) != 1 || f.Taps[0].Name != "sevmorris/tap" {
+		t.Fatalf("Taps = %v", f.Taps)
+	}
+	if tap := f.Taps[0]; tap.DocLine != 2 || !reflect.DeepEqual(tap.Doc, []string{"Required for doublender below."}) {
+		t.Errorf("tap annotation: DocLine=%d Doc=%q", tap.DocLine, tap.Doc)
+	}
+	cu := f.Find(KindBrew, "coreutils")
+	if cu == nil || cu.DocLine != cu.Line-1 || len(cu.Doc) != 1 {
+		t.Errorf("coreutils annotation not attached: %+v", cu)
+	}
+	if bash := f.Find(KindBrew, "bash"); bash == nil || bash.DocLine != bash.Line {
+		t.Errorf("bash should have no annotation: %+v", bash)
+	}
+	if s := f.Section("CLI Tools"); s == nil || s.Line != 5 || s.End != 10 {
+		t.Errorf("CLI Tools bounds = %+v", s)
+	}
+}
+
+func TestSectionNameCollisionsStayUnique(t *testing.T) {
+	f := Parse([]byte("## Tools - Media\nbrew \"a\"\n## Media\nbrew \"b\"\n## Tools - Media\nbrew \"c\"\n"))
+	var names []string
+	for _, s := range f.Sections {
+		names = append(names, s.Name)
+	}
+	want := []string{"Media", "Media (2)", "Tools - Media"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("section names = %q, want %q", names, want)
+	}
+}
+
+func TestParseOption(t *testing.T) {
+	good := map[string]Option{
+		"greedy: true":              {"greedy", "true"},
+		`args: ["a", "b"]`:          {"args", `["a", "b"]`},
+		"restart_service: :changed": {"restart_service", ":changed"},
+		":link => false":            {"link", "false"},
+	}
+	for in, want := range good {
+		if got, ok := ParseOption(in); !ok || got != want {
+			t.Errorf("ParseOption(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "greedy", "greedy:", `args: ["a"`, "a: 1, b: 2", "x: 1 # c"} {
+		if o, ok := ParseOption(in); ok {
+			t.Errorf("ParseOption(%q) = %v, want failure", in, o)
+		}
+	}
+}
+
+func TestRenameHeader(t *testing.T) {
+	cases := []struct{ header, name, want string }{
+		{"CLI Tools - Media", "Video", "CLI Tools - Video"},
+		{"CLI Tools - General Utilities & Power User Tools", "Shell", "Shell - General Utilities & Power User Tools"},
+		{"Languages / runtimes", "Runtimes", "Runtimes / runtimes"},
+		{"Repo essentials", "Essentials", "Essentials"},
+		{"Casks", "Apps - Desktop", "Apps - Desktop"},
+	}
+	for _, tc := range cases {
+		got := RenameHeader(tc.header, tc.name)
+		if got != tc.want {
+			t.Errorf("RenameHeader(%q, %q) = %q, want %q", tc.header, tc.name, got, tc.want)
+		}
+		if !strings.Contains(tc.name, " - ") && SectionName(got) != tc.name {
+			t.Errorf("SectionName(%q) = %q, want %q", got, SectionName(got), tc.name)
+		}
+	}
+}
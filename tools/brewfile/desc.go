@@ -0,0 +1,44 @@
+package brewfile
+
+import "strings"
+
+// ── Descriptions ─────────────────────────────────────────────────────────────
+//
+// A "desc:" comment gives an entry a description of its own, for tools that
+// show one next to the name (mrk-picker):
+//
+//	# desc: Podcast loudness normalisation
+//	cask "waxonwaxoff"
+//	brew "sox"  # desc: Sound eXchange @profile studio
+
+const descTag = "desc:"
+
+// DescTag returns the text of a comment that starts with "desc:", without a
+// trailing "@profile" tag. ok is false for any other comment and for an
+// empty description.
+func DescTag(comment string) (desc string, ok bool) {
+	comment = strings.TrimSpace(comment)
+	if len(comment) < len(descTag) || !strings.EqualFold(comment[:len(descTag)], descTag) {
+		return "", false
+	}
+	desc = comment[len(descTag):]
+	if idx := tagIndex(desc); idx >= 0 {
+		desc = desc[:idx]
+	}
+	desc = strings.TrimSpace(desc)
+	return desc, desc != ""
+}
+
+// Desc returns the entry's "desc:" annotation, from its inline comment or,
+// failing that, its annotation lines; "" when it has none.
+func (e *Entry) Desc() string {
+	if d, ok := DescTag(e.Comment); ok {
+		return d
+	}
+	for _, c := range e.Doc {
+		if d, ok := DescTag(c); ok {
+			return d
+		}
+	}
+	return ""
+}
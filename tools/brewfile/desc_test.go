@@ -0,0 +1,41 @@
+package brewfile
+
+import "testing"
+
+func TestDescTag(t *testing.T) {
+	cases := []struct {
+		comment string
+		want    string
+		ok      bool
+	}{
+		{"desc: Sound eXchange", "Sound eXchange", true},
+		{" Desc:  Virtual audio driver  ", "Virtual audio driver", true},
+		{"desc: Sound eXchange @profile studio", "Sound eXchange", true},
+		{"desc:", "", false},
+		{"describes the audio chain", "", false},
+		{"see desc: below", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := DescTag(tc.comment)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("DescTag(%q) = %q, %v; want %q, %v", tc.comment, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestEntryDesc(t *testing.T) {
+	f := Parse([]byte(`## Audio
+# desc: Podcast loudness normalisation
+# @profile studio
+cask "waxonwaxoff"
+brew "sox"  # desc: Sound eXchange
+# plain comment
+cask "vlc"
+`))
+	want := map[string]string{"waxonwaxoff": "Podcast loudness normalisation", "sox": "Sound eXchange", "vlc": ""}
+	for _, e := range f.Entries() {
+		if got := e.Desc(); got != want[e.Name] {
+			t.Errorf("%s: Desc() = %q, want %q", e.Name, got, want[e.Name])
+		}
+	}
+}
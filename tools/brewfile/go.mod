@@ -0,0 +1,3 @@
+module mrk-brewfile
+
+go 1.22
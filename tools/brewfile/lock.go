@@ -0,0 +1,140 @@
+package brewfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ── Lock file ────────────────────────────────────────────────────────────────
+//
+// Brewfile.lock.json records what was installed when the Brewfile was last
+// locked, so a machine rebuilt later can be compared with it. bf writes it;
+// mrk-status reads it.
+
+// LockName is the lock file's name, kept next to the Brewfile.
+const LockName = "Brewfile.lock.json"
+
+// lockFormat is the version of the lock file layout.
+const lockFormat = 1
+
+// Lock is a parsed Brewfile.lock.json.
+type Lock struct {
+	Format  int         `json:"format"`
+	Entries []LockEntry `json:"entries"`
+}
+
+// LockEntry is one locked Brewfile entry. Revision is the formula revision
+// for a brew and the commit a tap was at; Version already includes a
+// formula's revision suffix ("7.1_1"), as `brew list --versions` shows it.
+type LockEntry struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Version  string `json:"version,omitempty"`
+	Tap      string `json:"tap,omitempty"`
+	Revision string `json:"revision,omitempty"`
+}
+
+// LockPath returns the lock file path for the Brewfile at brewfilePath.
+func LockPath(brewfilePath string) string {
+	return filepath.Join(filepath.Dir(brewfilePath), LockName)
+}
+
+// LoadLock reads the lock file at path.
+func LoadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Older brew bundle versions write their own lock under the same name,
+	// without a format field.
+	var head struct{ Format int }
+	if json.Unmarshal(data, &head) == nil && head.Format == 0 {
+		return nil, fmt.Errorf("%s was not written by bf lock (brew bundle's own lock?)", filepath.Base(path))
+	}
+	var l Lock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if l.Format > lockFormat {
+		return nil, fmt.Errorf("%s: format %d is newer than this tool understands", filepath.Base(path), l.Format)
+	}
+	return &l, nil
+}
+
+// Find returns the locked entry with the given kind and name.
+func (l *Lock) Find(kind Kind, name string) (LockEntry, bool) {
+	for _, e := range l.Entries {
+		if e.Kind == kind.String() && e.Name == name {
+			return e, true
+		}
+	}
+	return LockEntry{}, false
+}
+
+// Bytes renders the lock as indented JSON with a trailing newline, so it
+// diffs line by line in git.
+func (l *Lock) Bytes() ([]byte, error) {
+	l.Format = lockFormat
+	if l.Entries == nil {
+		l.Entries = []LockEntry{}
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// WriteFile writes the lock to path atomically.
+func (l *Lock) WriteFile(path string) error {
+	data, err := l.Bytes()
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, data)
+}
+
+// ForProfile returns the locked entries that f still lists for profile. A
+// lock shared by several machines holds entries each of them installs.
+func (l *Lock) ForProfile(f *File, profile string) *Lock {
+	out := &Lock{Format: l.Format}
+	for _, le := range l.Entries {
+		kind, ok := ParseKind(le.Kind)
+		if !ok {
+			continue
+		}
+		if e := f.Find(kind, le.Name); e != nil && e.InProfile(profile) {
+			out.Entries = append(out.Entries, le)
+		}
+	}
+	return out
+}
+
+// VersionDrift is a locked entry whose installed version is not the locked
+// one. Installed is "" when the entry is not installed at all.
+type VersionDrift struct {
+	LockEntry
+	Installed string
+}
+
+// Drift compares the locked brews and casks with what is installed, in lock
+// order. installed reports an entry's installed version, and false when it
+// is not installed. Taps and entries without a locked version are skipped.
+func (l *Lock) Drift(installed func(kind Kind, name string) (string, bool)) []VersionDrift {
+	var out []VersionDrift
+	for _, e := range l.Entries {
+		kind, ok := ParseKind(e.Kind)
+		if !ok || (kind != KindBrew && kind != KindCask) || e.Version == "" {
+			continue
+		}
+		v, ok := installed(kind, e.Name)
+		if !ok {
+			out = append(out, VersionDrift{LockEntry: e})
+		} else if v != e.Version {
+			out = append(out, VersionDrift{LockEntry: e, Installed: v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package brewfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLockRoundTripAndDrift(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockName)
+	l := &Lock{Entries: []LockEntry{
+		{Name: "sevmorris/tap", Kind: "tap", Revision: "0a1b2c3"},
+		{Name: "ffmpeg", Kind: "brew", Version: "7.1_1", Tap: "homebrew/core", Revision: "1"},
+		{Name: "yt-dlp", Kind: "brew", Version: "2025.01.01", Tap: "homebrew/core"},
+		{Name: "firefox", Kind: "cask", Version: "130.0", Tap: "homebrew/cask"},
+	}}
+	if err := l.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != lockFormat || !reflect.DeepEqual(got.Entries, l.Entries) {
+		t.Fatalf("loaded %+v", got)
+	}
+	if e, ok := got.Find(KindCask, "firefox"); !ok || e.Version != "130.0" {
+		t.Errorf("Find(cask firefox) = %+v, %v", e, ok)
+	}
+
+	installed := map[string]string{"ffmpeg": "7.1_1", "firefox": "131.0"}
+	drift := got.Drift(func(_ Kind, name string) (string, bool) {
+		v, ok := installed[name]
+		return v, ok
+	})
+	want := []VersionDrift{
+		{LockEntry: l.Entries[2]},
+		{LockEntry: l.Entries[3], Installed: "131.0"},
+	}
+	if !reflect.DeepEqual(drift, want) {
+		t.Errorf("Drift = %+v, want %+v", drift, want)
+	}
+
+	doc := Parse([]byte("brew \"ffmpeg\"\ncask \"firefox\"  # @profile studio\n"))
+	var names []string
+	for _, le := range got.ForProfile(doc, "laptop").Entries {
+		names = append(names, le.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"ffmpeg"}) {
+		t.Errorf("ForProfile(laptop) = %q", names)
+	}
+
+	bundle := []byte(`{"entries": {"brew": {"ffmpeg": {"version": "7.1"}}}}`)
+	if err := os.WriteFile(path, bundle, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLock(path); err == nil || !strings.Contains(err.Error(), "not written by bf lock") {
+		t.Errorf("LoadLock(brew bundle lock) = %v", err)
+	}
+}
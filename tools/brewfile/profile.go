@@ -0,0 +1,152 @@
+package brewfile
+
+import (
+	"os"
+	"slices"
+	"strings"
+)
+
+// ── Profiles ─────────────────────────────────────────────────────────────────
+//
+// One Brewfile serves several machines. A "@profile" tag in a comment limits
+// entries to the named profiles:
+//
+//	## Audio
+//	# @profile studio
+//
+//	cask "ilok-license-manager"
+//	brew "sox"                   # @profile studio laptop
+//
+// A tag on a comment line of its own, not attached to an entry, applies to
+// every entry in its section. A tag in an entry's annotation or inline comment
+// applies to that entry and replaces the section's. Untagged entries belong
+// to every profile, and the profile "all" matches everything.
+
+// AllProfiles is the profile name that matches every entry.
+const AllProfiles = "all"
+
+const profileTag = "@profile"
+
+// ProfileTag returns the profile names in a comment's "@profile" tag,
+// lowercased. ok is false when the comment has no tag or the tag names none.
+func ProfileTag(comment string) (profiles []string, ok bool) {
+	idx := tagIndex(comment)
+	if idx < 0 {
+		return nil, false
+	}
+	rest := comment[idx+len(profileTag):]
+	for _, f := range strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		if p := strings.ToLower(f); !slices.Contains(profiles, p) {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles, len(profiles) > 0
+}
+
+// SetProfileTag returns comment with its "@profile" tag replaced by one
+// naming profiles, keeping any text before it. An empty list removes the tag.
+func SetProfileTag(comment string, profiles []string) string {
+	if idx := tagIndex(comment); idx >= 0 {
+		comment = comment[:idx]
+	}
+	comment = strings.TrimSpace(comment)
+	if len(profiles) == 0 {
+		return comment
+	}
+	tag := profileTag + " " + strings.Join(profiles, " ")
+	if comment == "" {
+		return tag
+	}
+	return comment + " " + tag
+}
+
+// tagIndex finds "@profile" as a word of its own. The tag runs to the end of
+// the comment.
+func tagIndex(comment string) int {
+	for off := 0; ; {
+		i := strings.Index(comment[off:], profileTag)
+		if i < 0 {
+			return -1
+		}
+		i += off
+		end := i + len(profileTag)
+		if (i == 0 || isSpace(comment[i-1])) && (end == len(comment) || isSpace(comment[end])) {
+			return i
+		}
+		off = end
+	}
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' }
+
+// OwnProfiles returns the profiles tagged on the entry itself, from its
+// inline comment or, failing that, its annotation.
+func (e *Entry) OwnProfiles() []string {
+	if p, ok := ProfileTag(e.Comment); ok {
+		return p
+	}
+	for _, d := range e.Doc {
+		if p, ok := ProfileTag(d); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// InProfile reports whether the entry belongs to profile. Every entry
+// belongs to "" and "all".
+func (e *Entry) InProfile(profile string) bool {
+	profile = strings.ToLower(profile)
+	if profile == "" || profile == AllProfiles || len(e.Profiles) == 0 {
+		return true
+	}
+	return slices.Contains(e.Profiles, profile) || slices.Contains(e.Profiles, AllProfiles)
+}
+
+// Profiles lists every profile named by a tag in the file, sorted.
+func (f *File) Profiles() []string {
+	var out []string
+	add := func(ps []string) {
+		for _, p := range ps {
+			if p != AllProfiles && !slices.Contains(out, p) {
+				out = append(out, p)
+			}
+		}
+	}
+	for _, s := range f.Sections {
+		add(s.Profiles)
+		for _, e := range s.Entries {
+			add(e.Profiles)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// ActiveProfile returns the profile this machine uses: $MRK_PROFILE when set,
+// otherwise the short hostname, lowercased.
+func ActiveProfile() string {
+	if p := strings.TrimSpace(os.Getenv("MRK_PROFILE")); p != "" {
+		return strings.ToLower(p)
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	host, _, _ = strings.Cut(host, ".")
+	return strings.ToLower(host)
+}
+
+// resolveProfiles fills in each entry's effective profiles once Reparse has
+// collected the section tags.
+func (f *File) resolveProfiles() {
+	for _, s := range f.Sections {
+		for _, e := range s.Entries {
+			if own := e.OwnProfiles(); own != nil {
+				e.Profiles = own
+			} else {
+				e.Profiles = s.Profiles
+			}
+		}
+	}
+}
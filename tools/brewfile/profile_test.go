@@ -0,0 +1,99 @@
+package brewfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProfileTag(t *testing.T) {
+	cases := []struct {
+		comment string
+		want    []string
+		ok      bool
+	}{
+		{"@profile studio", []string{"studio"}, true},
+		{"iLok dongle @profile Studio, laptop studio", []string{"studio", "laptop"}, true},
+		{"@profile", nil, false},
+		{"mail me@profiles.dev", nil, false},
+		{"no tag here", nil, false},
+	}
+	for _, tc := range cases {
+		got, ok := ProfileTag(tc.comment)
+		if ok != tc.ok || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ProfileTag(%q) = %q, %v; want %q, %v", tc.comment, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	if got := SetProfileTag("iLok dongle @profile studio", []string{"studio", "laptop"}); got != "iLok dongle @profile studio laptop" {
+		t.Errorf("SetProfileTag replace = %q", got)
+	}
+	if got := SetProfileTag("iLok dongle @profile studio", nil); got != "iLok dongle" {
+		t.Errorf("SetProfileTag remove = %q", got)
+	}
+	if got := SetProfileTag("", []string{"work"}); got != "@profile work" {
+		t.Errorf("SetProfileTag add = %q", got)
+	}
+}
+
+func TestProfilesFromSectionsAndEntries(t *testing.T) {
+	f := Parse([]byte(`brew "git"
+
+## Audio
+# @profile studio
+
+cask "ilok-license-manager"
+# @profile studio laptop
+brew "sox"
+cask "spotify"  # @profile all
+
+## Work
+cask "slack"  # only on the work laptop @profile work
+cask "firefox"
+`))
+	cases := []struct {
+		name string
+		want []string
+	}{
+		{"git", nil},
+		{"ilok-license-manager", []string{"studio"}},
+		{"sox", []string{"studio", "laptop"}},
+		{"spotify", []string{"all"}},
+		{"slack", []string{"work"}},
+		{"firefox", nil},
+	}
+	for _, tc := range cases {
+		var e *Entry
+		for _, x := range f.Entries() {
+			if x.Name == tc.name {
+				e = x
+			}
+		}
+		if e == nil || !reflect.DeepEqual(e.Profiles, tc.want) {
+			t.Errorf("%s: profiles = %v, want %v", tc.name, e, tc.want)
+		}
+	}
+
+	var laptop []string
+	for _, e := range f.Entries() {
+		if e.InProfile("Laptop") {
+			laptop = append(laptop, e.Name)
+		}
+	}
+	if want := []string{"git", "sox", "spotify", "firefox"}; !reflect.DeepEqual(laptop, want) {
+		t.Errorf("laptop entries = %q, want %q", laptop, want)
+	}
+	if got, want := f.Profiles(), []string{"laptop", "studio", "work"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Profiles() = %q, want %q", got, want)
+	}
+}
+
+func TestActiveProfilePrefersEnvironment(t *testing.T) {
+	t.Setenv("MRK_PROFILE", " Studio ")
+	if got := ActiveProfile(); got != "studio" {
+		t.Errorf("ActiveProfile() = %q", got)
+	}
+	t.Setenv("MRK_PROFILE", "")
+	if got := ActiveProfile(); got == "" {
+		t.Error("ActiveProfile() without MRK_PROFILE should fall back to the hostname")
+	}
+}
@@ -4,6 +4,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -14,6 +15,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	brewfile "mrk-brewfile"
 	theme "mrk-theme"
 )
 
@@ -293,28 +295,27 @@ func checkHomebrew() group {
 		[]statusLine{sl(sevOK, ver)}, ""}
 }
 
-var (
-	reBrewPkg = regexp.MustCompile(`^brew\s+"([^"]+)"`)
-	reCaskPkg = regexp.MustCompile(`^cask\s+"([^"]+)"`)
-)
-
 func checkBrewfile(repoRoot string) group {
 	path := filepath.Join(repoRoot, "Brewfile")
-	f, err := os.Open(path)
+	doc, err := brewfile.Load(path)
 	if err != nil {
 		return group{"Brewfile", sevWarn,
 			[]statusLine{sl(sevWarn, "Brewfile not found at "+path)}, ""}
 	}
-	defer f.Close()
 
+	// Only this machine's profile counts; entries tagged for other machines
+	// are neither expected nor missing here.
+	profile := brewfile.ActiveProfile()
 	var formulae, casks []string
-	sc := bufio.NewScanner(f)
-	for sc.Scan() {
-		l := strings.TrimSpace(sc.Text())
-		if m := reBrewPkg.FindStringSubmatch(l); m != nil {
-			formulae = append(formulae, m[1])
-		} else if m := reCaskPkg.FindStringSubmatch(l); m != nil {
-			casks = append(casks, m[1])
+	for _, e := range doc.Entries() {
+		if !e.InProfile(profile) {
+			continue
+		}
+		switch e.Kind {
+		case brewfile.KindBrew:
+			formulae = append(formulae, e.Name)
+		case brewfile.KindCask:
+			casks = append(casks, e.Name)
 		}
 	}
 
@@ -337,24 +338,37 @@ func checkBrewfile(repoRoot string) group {
 		}
 	}
 
+	// Detail lines follow the Brewfile's own sections, named as bf and
+	// mrk-picker name them.
 	var lines []statusLine
 	installed, missing := 0, 0
-	for _, pkg := range formulae {
-		if instF[pkg] {
-			installed++
-			lines = append(lines, sl(sevOK, pkg))
-		} else {
-			missing++
-			lines = append(lines, sl(sevErr, pkg+" (missing)"))
+	for _, s := range doc.Sections {
+		var secLines []statusLine
+		for _, e := range s.Entries {
+			if !e.InProfile(profile) {
+				continue
+			}
+			var ok bool
+			var note string
+			switch e.Kind {
+			case brewfile.KindBrew:
+				ok = instF[e.Name]
+			case brewfile.KindCask:
+				ok, note = instC[e.Name], "cask"
+			default:
+				continue
+			}
+			if ok {
+				installed++
+				secLines = append(secLines, sl(sevOK, pkgLabel(e.Name, note)))
+			} else {
+				missing++
+				secLines = append(secLines, sl(sevErr, pkgLabel(e.Name, note, "missing")))
+			}
 		}
-	}
-	for _, pkg := range casks {
-		if instC[pkg] {
-			installed++
-			lines = append(lines, sl(sevOK, pkg+" (cask)"))
-		} else {
-			missing++
-			lines = append(lines, sl(sevErr, pkg+" (cask, missing)"))
+		if len(secLines) > 0 {
+			lines = append(lines, sl(sevInfo, "── "+s.Name))
+			lines = append(lines, secLines...)
 		}
 	}
 
@@ -363,6 +377,9 @@ func checkBrewfile(repoRoot string) group {
 	if missing > 0 {
 		summary += fmt.Sprintf(", %d missing", missing)
 	}
+	if len(doc.Profiles()) > 0 {
+		summary += " (profile " + profile + ")"
+	}
 	all := append([]statusLine{sl(sevInfo, summary)}, lines...)
 	sev, fix := sevOK, ""
 	if missing > 0 {
@@ -372,6 +389,82 @@ func checkBrewfile(repoRoot string) group {
 	return group{"Brewfile", sev, all, fix}
 }
 
+// checkLock compares installed versions with Brewfile.lock.json, which
+// `bf lock` writes, so a rebuilt machine shows what moved since then.
+func checkLock(repoRoot string) group {
+	path := filepath.Join(repoRoot, brewfile.LockName)
+	lock, err := brewfile.LoadLock(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return group{"Versions", sevInfo, []statusLine{
+			sl(sevInfo, "No "+brewfile.LockName+" — versions are not recorded"),
+		}, "bf lock"}
+	}
+	if err != nil {
+		return group{"Versions", sevWarn, []statusLine{sl(sevWarn, err.Error())}, ""}
+	}
+	// Only what this machine's profile still lists is expected here.
+	if doc, err := brewfile.Load(filepath.Join(repoRoot, "Brewfile")); err == nil {
+		lock = lock.ForProfile(doc, brewfile.ActiveProfile())
+	}
+	if _, err := exec.LookPath("brew"); err != nil {
+		return group{"Versions", sevInfo, []statusLine{
+			sl(sevInfo, fmt.Sprintf("%d locked entries (brew unavailable — skipping checks)", len(lock.Entries))),
+		}, ""}
+	}
+
+	// brew list --versions names tap formulae by their short name.
+	installed := map[brewfile.Kind]map[string]string{}
+	for _, k := range []brewfile.Kind{brewfile.KindBrew, brewfile.KindCask} {
+		installed[k] = map[string]string{}
+		flag := "--formula"
+		if k == brewfile.KindCask {
+			flag = "--cask"
+		}
+		out, err := exec.Command("brew", "list", flag, "--versions").Output()
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			if f := strings.Fields(line); len(f) > 1 {
+				installed[k][f[0]] = f[len(f)-1]
+			}
+		}
+	}
+	drift := lock.Drift(func(kind brewfile.Kind, name string) (string, bool) {
+		v, ok := installed[kind][name[strings.LastIndex(name, "/")+1:]]
+		return v, ok
+	})
+
+	if len(drift) == 0 {
+		return group{"Versions", sevOK, []statusLine{
+			sl(sevOK, fmt.Sprintf("All installed versions match %s", brewfile.LockName)),
+		}, ""}
+	}
+	lines := []statusLine{sl(sevInfo, fmt.Sprintf("%d entries differ from %s", len(drift), brewfile.LockName))}
+	for _, d := range drift {
+		now := d.Installed
+		if now == "" {
+			now = "not installed"
+		}
+		lines = append(lines, sl(sevWarn, pkgLabel(d.Name, d.Kind, now+", locked "+d.Version)))
+	}
+	return group{"Versions", sevWarn, lines, "bf lock"}
+}
+
+// pkgLabel renders "name (note, note)", skipping empty notes.
+func pkgLabel(name string, notes ...string) string {
+	var kept []string
+	for _, n := range notes {
+		if n != "" {
+			kept = append(kept, n)
+		}
+	}
+	if len(kept) == 0 {
+		return name
+	}
+	return name + " (" + strings.Join(kept, ", ") + ")"
+}
+
 // ── Messages & commands ───────────────────────────────────────────────────
 
 type checksMsg []group
@@ -390,6 +483,7 @@ func runChecks(repoRoot, home, binDir string) tea.Cmd {
 			checkPATH(binDir),
 			checkHomebrew(),
 			checkBrewfile(repoRoot),
+			checkLock(repoRoot),
 		})
 	}
 }
@@ -812,6 +906,9 @@ TUI keys:
   f                   Run fix command for selected check
   r                   Refresh all checks
   q / esc             Quit
+
+Environment:
+  MRK_PROFILE         Brewfile profile to check (default: the short hostname)
 `)
 }
 
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	brewfile "mrk-brewfile"
+)
+
+// ── Description sources ───────────────────────────────────────────────────
+//
+// A package's description comes from, in order: a "# desc:" annotation in
+// the Brewfile, the cache --update-descs fills from brew, and the built-in
+// descriptions map.
+
+// descCachePath is where --update-descs writes brew's descriptions.
+func descCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mrk", "picker-descs.json")
+}
+
+// loadDescCache reads the description cache; a missing or unreadable cache
+// is empty, so the picker still runs on a fresh machine.
+func loadDescCache(path string) map[string]string {
+	descs := map[string]string{}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &descs)
+	}
+	return descs
+}
+
+// describe picks the description shown for e.
+func describe(e *brewfile.Entry, cache map[string]string) string {
+	if d := e.Desc(); d != "" {
+		return d
+	}
+	if d := cache[e.Name]; d != "" {
+		return d
+	}
+	return descriptions[e.Name]
+}
+
+// brewDescs asks brew for the descriptions of names, all at once and, when
+// brew rejects the batch for an unknown name, one at a time.
+func brewDescs(kind pkgKind, names []string) map[string]string {
+	out := map[string]string{}
+	query := func(names []string) error {
+		args := append([]string{"info", "--json=v2", "--" + string(kind)}, names...)
+		data, err := exec.Command("brew", args...).Output()
+		if err != nil {
+			return err
+		}
+		return parseBrewDescs(data, out)
+	}
+	if len(names) == 0 || query(names) == nil {
+		return out
+	}
+	for _, n := range names {
+		query([]string{n})
+	}
+	return out
+}
+
+// parseBrewDescs adds the descriptions in `brew info --json=v2` output to
+// out, under both the short and the tap-qualified name, since a Brewfile
+// may list a tap's package either way.
+func parseBrewDescs(data []byte, out map[string]string) error {
+	var info struct {
+		Formulae []struct {
+			Name     string `json:"name"`
+			FullName string `json:"full_name"`
+			Desc     string `json:"desc"`
+		} `json:"formulae"`
+		Casks []struct {
+			Token     string `json:"token"`
+			FullToken string `json:"full_token"`
+			Desc      string `json:"desc"`
+		} `json:"casks"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return err
+	}
+	add := func(desc string, names ...string) {
+		for _, n := range names {
+			if n != "" {
+				out[n] = desc
+			}
+		}
+	}
+	for _, f := range info.Formulae {
+		add(f.Desc, f.Name, f.FullName)
+	}
+	for _, c := range info.Casks {
+		add(c.Desc, c.Token, c.FullToken)
+	}
+	return nil
+}
+
+// updateDescCache fills the cache with brew's description of every formula
+// and cask in the Brewfile, in every profile.
+func updateDescCache(doc *brewfile.File, path string, stdout io.Writer) error {
+	if path == "" {
+		return fmt.Errorf("no cache directory")
+	}
+	if _, err := exec.LookPath("brew"); err != nil {
+		return fmt.Errorf("brew not found")
+	}
+	var formulae, casks []string
+	for _, e := range doc.Entries() {
+		switch e.Kind {
+		case brewfile.KindBrew:
+			formulae = append(formulae, e.Name)
+		case brewfile.KindCask:
+			casks = append(casks, e.Name)
+		}
+	}
+	descs := brewDescs(formula, formulae)
+	for name, d := range brewDescs(cask, casks) {
+		descs[name] = d
+	}
+	data, err := json.MarshalIndent(descs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	found := 0
+	for _, n := range slices.Concat(formulae, casks) {
+		if descs[n] != "" {
+			found++
+		}
+	}
+	fmt.Fprintf(stdout, "mrk-picker: cached %d of %d descriptions → %s\n", found, len(formulae)+len(casks), path)
+	return nil
+}
+
+// checkDescs reports Brewfile formulae and casks that no source describes,
+// and built-in descriptions for packages the Brewfile no longer lists. It
+// returns false when it found either.
+func checkDescs(doc *brewfile.File, cache map[string]string, stdout, stderr io.Writer) bool {
+	var missing, orphans []string
+	listed := map[string]bool{}
+	for _, e := range doc.Entries() {
+		if e.Kind != brewfile.KindBrew && e.Kind != brewfile.KindCask || listed[e.Name] {
+			continue
+		}
+		listed[e.Name] = true
+		if describe(e, cache) == "" {
+			missing = append(missing, e.Name)
+		}
+	}
+	for name := range descriptions {
+		if !listed[name] {
+			orphans = append(orphans, name)
+		}
+	}
+	slices.Sort(missing)
+	slices.Sort(orphans)
+
+	if len(missing) == 0 && len(orphans) == 0 {
+		fmt.Fprintf(stdout, "mrk-picker --check: OK (%d packages, all described)\n", len(listed))
+		return true
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(stderr, "mrk-picker --check: no description (%d) — add a \"# desc:\" comment or run --update-descs:\n", len(missing))
+		for _, n := range missing {
+			fmt.Fprintf(stderr, "  %s\n", n)
+		}
+	}
+	if len(orphans) > 0 {
+		fmt.Fprintf(stderr, "mrk-picker --check: built-in description, not in Brewfile (%d):\n", len(orphans))
+		for _, n := range orphans {
+			fmt.Fprintf(stderr, "  %s\n", n)
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	brewfile "mrk-brewfile"
+)
+
+func TestParseBrewDescs(t *testing.T) {
+	data := []byte(`{"formulae":[{"name":"bf","full_name":"sevmorris/tap/bf","desc":"Brewfile manager"},
+		{"name":"git","full_name":"git","desc":"Distributed revision control"}],
+		"casks":[{"token":"font-x","full_token":"sevmorris/tap/font-x","desc":"A font"}]}`)
+	descs := map[string]string{}
+	if err := parseBrewDescs(data, descs); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"sevmorris/tap/bf":     "Brewfile manager",
+		"bf":                   "Brewfile manager",
+		"git":                  "Distributed revision control",
+		"sevmorris/tap/font-x": "A font",
+	} {
+		if descs[name] != want {
+			t.Errorf("descs[%q] = %q, want %q", name, descs[name], want)
+		}
+	}
+
+	// A tap-qualified Brewfile entry finds its cached description.
+	e := brewfile.Parse([]byte(`brew "sevmorris/tap/bf"` + "\n")).Entries()[0]
+	if got := describe(e, descs); got != "Brewfile manager" {
+		t.Errorf("describe(tap entry) = %q", got)
+	}
+}
+
+func TestDescribePrecedence(t *testing.T) {
+	doc := brewfile.Parse([]byte(`# desc: From the annotation
+brew "bat"
+brew "bash"
+brew "unknown-tool"
+`))
+	cache := map[string]string{"bat": "From the cache", "bash": "From the cache"}
+	want := map[string]string{
+		"bat":          "From the annotation",
+		"bash":         "From the cache",
+		"unknown-tool": "",
+	}
+	for _, e := range doc.Entries() {
+		if got := describe(e, cache); got != want[e.Name] {
+			t.Errorf("describe(%s) = %q, want %q", e.Name, got, want[e.Name])
+		}
+	}
+	if e := doc.Find(brewfile.KindBrew, "bash"); describe(e, nil) != descriptions["bash"] || descriptions["bash"] == "" {
+		t.Errorf("describe(bash) without a cache = %q, want the built-in %q", describe(e, nil), descriptions["bash"])
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	theme "mrk-theme"
+)
+
+// ── Filter and review ─────────────────────────────────────────────────────
+
+// flatPkg is a package with its place in the category panes, for the lists
+// that span every category.
+type flatPkg struct {
+	cat int
+	idx int
+	p   *pkg
+}
+
+func (m model) flatten(keep func(cat int, p *pkg) bool) []flatPkg {
+	var out []flatPkg
+	for ci, c := range m.cats {
+		for pi, p := range c.pkgs {
+			if keep(ci, p) {
+				out = append(out, flatPkg{cat: ci, idx: pi, p: p})
+			}
+		}
+	}
+	return out
+}
+
+// applyFilter recomputes the filter list from filterInput: a case-insensitive
+// substring match against name, description and category, as in mrk-menu.
+func (m *model) applyFilter() {
+	q := strings.ToLower(strings.TrimSpace(m.filterInput))
+	m.list = m.flatten(func(ci int, p *pkg) bool {
+		return q == "" || strings.Contains(strings.ToLower(p.name+" "+p.desc+" "+m.cats[ci].name), q)
+	})
+	m.listIdx = max(0, min(m.listIdx, len(m.list)-1))
+}
+
+func (m model) startFilter() model {
+	m.filterInput = ""
+	m.listIdx = 0
+	m.mode = modeFilter
+	m.applyFilter()
+	return m
+}
+
+// startReview lists the selected packages. The list stays put while it is
+// open, so a package unselected by mistake can be selected again.
+func (m model) startReview() model {
+	m.list = m.flatten(func(_ int, p *pkg) bool { return p.selected })
+	m.listIdx = 0
+	m.mode = modeReview
+	return m
+}
+
+// leaveList returns to the panes with the cursor on the package the list
+// cursor was on.
+func (m model) leaveList() model {
+	if m.listIdx < len(m.list) {
+		fp := m.list[m.listIdx]
+		m.catIdx, m.pkgIdx, m.leftFocus = fp.cat, fp.idx, false
+	}
+	m.mode = modeNormal
+	m.list = nil
+	return m
+}
+
+func (m model) handleListKey(msg tea.KeyMsg) (model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		m.cancelled = true
+		return m, tea.Quit
+	case tea.KeyEsc:
+		return m.leaveList(), nil
+	case tea.KeyDown, tea.KeyCtrlN:
+		if m.listIdx < len(m.list)-1 {
+			m.listIdx++
+		}
+	case tea.KeyUp, tea.KeyCtrlP:
+		if m.listIdx > 0 {
+			m.listIdx--
+		}
+	case tea.KeySpace:
+		if m.listIdx < len(m.list) {
+			if p := m.list[m.listIdx].p; !p.installed {
+				p.selected = !p.selected
+				if m.listIdx < len(m.list)-1 {
+					m.listIdx++
+				}
+			}
+		}
+	case tea.KeyEnter:
+		if m.mode == modeReview {
+			m.confirmed = true
+			return m, tea.Quit
+		}
+		return m.leaveList(), nil
+	case tea.KeyBackspace, tea.KeyDelete:
+		if m.mode == modeFilter && m.filterInput != "" {
+			_, size := utf8.DecodeLastRuneInString(m.filterInput)
+			m.filterInput = m.filterInput[:len(m.filterInput)-size]
+			m.applyFilter()
+		}
+	case tea.KeyRunes:
+		switch {
+		case m.mode == modeFilter:
+			m.filterInput += string(msg.Runes)
+			m.applyFilter()
+		case msg.String() == "j" && m.listIdx < len(m.list)-1:
+			m.listIdx++
+		case msg.String() == "k" && m.listIdx > 0:
+			m.listIdx--
+		case msg.String() == "q":
+			return m.leaveList(), nil
+		}
+	}
+	return m, nil
+}
+
+// viewList renders the filter or review list across the full width.
+func (m model) viewList(inner, height int) string {
+	var sb strings.Builder
+	if m.mode == modeFilter {
+		sb.WriteString(styleCatActive.Render("/ ") + styleCatNorm.Render(m.filterInput) + stylePkgCurs.Render("█"))
+		total := 0
+		for _, c := range m.cats {
+			total += len(c.pkgs)
+		}
+		sb.WriteString(styleDescDim.Render(fmt.Sprintf("   %d of %d", len(m.list), total)) + "\n\n")
+	} else {
+		sb.WriteString(styleCatActive.Render(fmt.Sprintf("Review: %d selected", m.totalSelected())) + "\n\n")
+	}
+	listH := max(height-2, 1)
+
+	if len(m.list) == 0 {
+		msg := "No matches"
+		if m.mode == modeReview {
+			msg = "Nothing selected"
+		}
+		sb.WriteString(styleDescDim.Render(msg))
+		return stylePaneOn.Width(inner).Height(height).Render(sb.String())
+	}
+
+	const nameW, catW = 24, 18
+	descW := max(0, inner-nameW-catW-6)
+	start := max(0, m.listIdx-listH+1)
+	for i, fp := range m.list[start:min(len(m.list), start+listH)] {
+		i += start
+		p := fp.p
+		name := theme.Truncate(p.name, nameW)
+		name += strings.Repeat(" ", max(0, nameW-len([]rune(name))))
+		cat := theme.Truncate(m.cats[fp.cat].name, catW)
+		cat += strings.Repeat(" ", max(0, catW-len([]rune(cat))))
+		desc := theme.Truncate(p.desc, descW)
+
+		indicator := "  "
+		nameStyle, descStyle := styleCatNorm, styleDescDim
+		switch {
+		case p.installed:
+			indicator = styleInstalled.Render("● ")
+			nameStyle, descStyle = styleInstalled, styleInstalled
+		case p.selected:
+			indicator = stylePkgSel.Render("✓ ")
+			nameStyle, descStyle = stylePkgSel, stylePkgSel
+		}
+		if i == m.listIdx {
+			indicator = stylePkgCurs.Render("▸ ")
+			nameStyle = stylePkgCurs
+		}
+		sb.WriteString(indicator + nameStyle.Render(name) + "  " + styleBadgeDim.Render(cat) + "  " + descStyle.Render(desc) + "\n")
+	}
+	return stylePaneOn.Width(inner).Height(height).Render(strings.TrimRight(sb.String(), "\n"))
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// listed names the packages in the filter or review list.
+func listed(m model) []string {
+	var out []string
+	for _, fp := range m.list {
+		out = append(out, string(fp.p.kind)+":"+fp.p.name)
+	}
+	return out
+}
+
+func TestApplyFilter(t *testing.T) {
+	_, cats := fixture(t)
+	m := newModel(cats)
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"formula:git", "formula:docker", "formula:sevmorris/tap/bf", "cask:firefox", "cask:docker"}},
+		{"DOCK", []string{"formula:docker", "cask:docker"}},
+		{"version", []string{"formula:git"}},                // description
+		{" apps ", []string{"cask:firefox", "cask:docker"}}, // category
+		{"nothing like it", nil},
+	}
+	for _, tc := range cases {
+		m.filterInput = tc.query
+		m.listIdx = 4
+		m.applyFilter()
+		if got := listed(m); !slices.Equal(got, tc.want) {
+			t.Errorf("filter %q = %q, want %q", tc.query, got, tc.want)
+		}
+		if m.listIdx < 0 || len(m.list) > 0 && m.listIdx >= len(m.list) {
+			t.Errorf("filter %q: listIdx %d out of range", tc.query, m.listIdx)
+		}
+	}
+}
+
+func TestFilterTogglesAndLeavesAtCursor(t *testing.T) {
+	_, cats := fixture(t)
+	m := press(newModel(cats), "/", "d", "o", "c", "k")
+	if m.mode != modeFilter || m.filterInput != "dock" || len(m.list) != 2 {
+		t.Fatalf("after typing: mode=%v input=%q list=%q", m.mode, m.filterInput, listed(m))
+	}
+
+	// Space selects and moves down; on the last row it stays put.
+	m = press(m, " ", " ")
+	if got := selected(m.cats); !slices.Equal(got, []string{"formula:docker", "cask:docker"}) || m.listIdx != 1 {
+		t.Errorf("selected %q, listIdx %d", got, m.listIdx)
+	}
+
+	// Installed packages cannot be toggled.
+	m = press(m, "backspace", "backspace", "backspace", "backspace", "g", "i", "t")
+	if m = press(m, " "); m.cats[0].pkgs[0].selected {
+		t.Error("installed git was selected")
+	}
+
+	// Leaving puts the pane cursor on the package the list cursor was on.
+	m = press(m, "backspace", "backspace", "backspace", "f", "i", "r", "e", "enter")
+	if m.mode != modeNormal || m.list != nil || m.catIdx != 1 || m.pkgIdx != 0 || m.leftFocus {
+		t.Errorf("enter: mode=%v cat=%d pkg=%d leftFocus=%v", m.mode, m.catIdx, m.pkgIdx, m.leftFocus)
+	}
+	m = press(m, "/", "d", "o", "c", "k", "down", "esc")
+	if m.mode != modeNormal || m.catIdx != 1 || m.pkgIdx != 1 {
+		t.Errorf("esc: mode=%v cat=%d pkg=%d", m.mode, m.catIdx, m.pkgIdx)
+	}
+
+	// With no match, leaving keeps the cursor where it was.
+	m = press(m, "/", "z", "z", "z", "esc")
+	if m.catIdx != 1 || m.pkgIdx != 1 {
+		t.Errorf("empty list moved the cursor: cat=%d pkg=%d", m.catIdx, m.pkgIdx)
+	}
+}
+
+func TestReviewBeforeConfirm(t *testing.T) {
+	_, cats := fixture(t)
+	applySelection(cats, []string{"formula:docker", "firefox"})
+	m := newModel(cats)
+
+	next, cmd := m.Update(keyMsg("enter"))
+	m = next.(model)
+	if m.mode != modeReview || cmd != nil || m.confirmed {
+		t.Fatalf("enter: mode=%v confirmed=%v", m.mode, m.confirmed)
+	}
+	if got := listed(m); !slices.Equal(got, []string{"formula:docker", "cask:firefox"}) {
+		t.Errorf("review list = %q", got)
+	}
+
+	// Unselecting keeps the row, so it can be selected again.
+	m = press(m, " ")
+	if len(m.list) != 2 || m.totalSelected() != 1 {
+		t.Errorf("after unselect: list %q, %d selected", listed(m), m.totalSelected())
+	}
+	m = press(m, "k", " ")
+	if m.totalSelected() != 2 {
+		t.Errorf("reselect: %d selected", m.totalSelected())
+	}
+
+	// Back out, then review again and confirm.
+	if m = press(m, "esc"); m.mode != modeNormal || m.confirmed {
+		t.Errorf("esc: mode=%v confirmed=%v", m.mode, m.confirmed)
+	}
+	m = press(m, "v")
+	next, cmd = m.Update(keyMsg("enter"))
+	if m = next.(model); !m.confirmed || cmd == nil {
+		t.Errorf("enter in review: confirmed=%v", m.confirmed)
+	}
+}
+
+func TestConfirmWithNothingSelectedSkipsReview(t *testing.T) {
+	_, cats := fixture(t)
+	next, cmd := newModel(cats).Update(keyMsg("enter"))
+	if m := next.(model); m.mode != modeNormal || !m.confirmed || cmd == nil {
+		t.Errorf("mode=%v confirmed=%v", m.mode, m.confirmed)
+	}
+}
+
+func TestFlashFooterOffersReview(t *testing.T) {
+	_, cats := fixture(t)
+	m := newModel(cats)
+	m.flash = "saved 0 package(s)"
+	if footer := m.viewFooter(); !strings.Contains(footer, "enter review") {
+		t.Errorf("footer = %q", footer)
+	}
+}
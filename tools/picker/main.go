@@ -1,18 +1,20 @@
 // mrk-picker — interactive Brewfile package selector
 // Two-pane Bubble Tea TUI: categories (left) | packages with descriptions (right)
-// Outputs selected packages as "formula:name" or "cask:name" lines to stdout.
+// Outputs selected packages as "formula:name" or "cask:name" lines to stdout,
+// or in another --format (json, brewfile, nul; see output.go).
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
+	"slices"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	brewfile "mrk-brewfile"
 	theme "mrk-theme"
 )
 
@@ -41,6 +43,8 @@ type category struct {
 
 // ── Descriptions ──────────────────────────────────────────────────────────
 
+// descriptions is the fallback for packages with neither a "# desc:"
+// annotation nor a cached description from brew (see descs.go).
 var descriptions = map[string]string{
 	// Formulae
 	"bash":              "Modern shell (Bash 5.x) with improved features",
@@ -178,125 +182,61 @@ var descriptions = map[string]string{
 
 // ── Brewfile parsing ──────────────────────────────────────────────────────
 
-var (
-	formulaRe = regexp.MustCompile(`^brew "([^"]+)"`)
-	caskRe    = regexp.MustCompile(`^cask "([^"]+)"`)
-)
-
-// categoryName extracts a short, friendly name from a Brewfile comment line.
-func categoryName(comment string) string {
-	name := comment
-	// "X - Y": use Y when ≤2 words (specific), otherwise use X (general)
-	if idx := strings.LastIndex(name, " - "); idx != -1 {
-		suffix := name[idx+3:]
-		if len(strings.Fields(suffix)) <= 2 {
-			name = suffix
-		} else {
-			name = name[:idx]
-		}
-	}
-	// Strip "/ ..." or "& ..." qualifiers
-	if idx := strings.Index(name, " / "); idx != -1 {
-		name = name[:idx]
-	}
-	if idx := strings.Index(name, " & "); idx != -1 {
-		name = name[:idx]
-	}
-	// "Casks" alone is not meaningful in context
-	if strings.TrimSpace(name) == "Casks" {
-		name = "Applications"
-	}
-	return strings.TrimSpace(name)
-}
-
+// parseBrewfile groups the Brewfile's formulae and casks into categories
+// named by the shared parser, so the picker shows the same sections as bf.
+// Entries tagged for other profiles are left out.
 func parseBrewfile(
-	path string,
+	doc *brewfile.File,
 	installedFormulae, installedCasks map[string]bool,
 	skipFormulae, skipCasks bool,
-) ([]category, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
+	profile string,
+	descCache map[string]string,
+) []category {
 	var cats []category
-	var curCat *category
-
-	push := func(p *pkg) {
-		if curCat == nil {
-			cats = append(cats, category{name: "General"})
-			curCat = &cats[len(cats)-1]
-		}
-		curCat.pkgs = append(curCat.pkgs, p)
-	}
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-		trimmed := strings.TrimSpace(line)
-
-		if strings.HasPrefix(trimmed, "#") {
-			// Only ## lines are section headers; single-# lines are annotations.
-			if !strings.HasPrefix(trimmed, "##") {
+	for _, s := range doc.Sections {
+		cat := category{name: s.Name}
+		for _, e := range s.Entries {
+			if !e.InProfile(profile) {
 				continue
 			}
-			text := strings.TrimSpace(strings.TrimPrefix(trimmed, "##"))
-			// Skip blank headers and the Taps header (taps aren't selectable packages)
-			if text == "" || text == "Taps" {
+			p := &pkg{name: e.Name, line: doc.Lines[e.Line], desc: describe(e, descCache)}
+			switch {
+			case e.Kind == brewfile.KindBrew && !skipFormulae:
+				p.kind = formula
+				p.installed = installedFormulae[e.Name]
+			case e.Kind == brewfile.KindCask && !skipCasks:
+				p.kind = cask
+				p.installed = installedCasks[e.Name]
+			default:
 				continue
 			}
-			name := categoryName(text)
-			if name == "" {
-				continue
-			}
-			cats = append(cats, category{name: name})
-			curCat = &cats[len(cats)-1]
-			continue
-		}
-
-		if m := formulaRe.FindStringSubmatch(line); m != nil {
-			if !skipFormulae {
-				name := m[1]
-				push(&pkg{
-					name:      name,
-					kind:      formula,
-					line:      line,
-					desc:      descriptions[name],
-					installed: installedFormulae[name],
-				})
-			}
-			continue
+			cat.pkgs = append(cat.pkgs, p)
 		}
-
-		if m := caskRe.FindStringSubmatch(line); m != nil {
-			if !skipCasks {
-				name := m[1]
-				push(&pkg{
-					name:      name,
-					kind:      cask,
-					line:      line,
-					desc:      descriptions[name],
-					installed: installedCasks[name],
-				})
-			}
+		// Drop empty categories (including Taps)
+		if len(cat.pkgs) > 0 {
+			cats = append(cats, cat)
 		}
 	}
-
-	// Drop empty categories
-	out := cats[:0]
-	for _, c := range cats {
-		if len(c.pkgs) > 0 {
-			out = append(out, c)
-		}
-	}
-	return out, scanner.Err()
+	return cats
 }
 
 // ── Model ─────────────────────────────────────────────────────────────────
 
+// pickerMode is what the keyboard drives: the two panes, a preset prompt,
+// or a flat list across categories.
+type pickerMode int
+
+const (
+	modeNormal     pickerMode = iota
+	modeSavePreset            // typing a preset name
+	modeLoadPreset            // choosing a preset
+	modeFilter                // "/" filter across all categories
+	modeReview                // the selected packages, before confirming
+)
+
 type model struct {
 	cats      []category
+	profile   string
 	catIdx    int  // left-pane cursor
 	pkgIdx    int  // right-pane cursor
 	leftFocus bool // which pane has keyboard focus
@@ -304,10 +244,24 @@ type model struct {
 	height    int
 	confirmed bool
 	cancelled bool
+
+	mode pickerMode
+
+	// Filter and review lists
+	filterInput string
+	listIdx     int
+	list        []flatPkg
+
+	// Presets
+	presetDir string   // ~/.mrk/picker-presets
+	presets   []string // names offered by the load list
+	presetIdx int
+	input     string // preset name being typed
+	flash     string
 }
 
 func newModel(cats []category) model {
-	return model{cats: cats, leftFocus: true}
+	return model{cats: cats, leftFocus: true, presetDir: defaultPresetDir()}
 }
 
 func (m model) Init() tea.Cmd { return nil }
@@ -338,6 +292,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 
 	case tea.KeyMsg:
+		switch m.mode {
+		case modeSavePreset, modeLoadPreset:
+			return m.handlePresetKey(msg)
+		case modeFilter, modeReview:
+			return m.handleListKey(msg)
+		}
+		m.flash = ""
 		switch msg.String() {
 		case "q", "ctrl+c":
 			m.cancelled = true
@@ -350,8 +311,16 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Quit
 			}
 		case "enter":
+			// Show what is about to be installed before confirming.
+			if m.totalSelected() > 0 {
+				return m.startReview(), nil
+			}
 			m.confirmed = true
 			return m, tea.Quit
+		case "/":
+			return m.startFilter(), nil
+		case "v":
+			return m.startReview(), nil
 
 		case "tab", "shift+tab":
 			m.leftFocus = !m.leftFocus
@@ -398,6 +367,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
+		case "S":
+			m.input = ""
+			m.mode = modeSavePreset
+		case "p":
+			m = m.startPresetLoad()
+
 		case "a":
 			if !m.leftFocus {
 				pkgs := m.currentPkgs()
@@ -463,14 +438,23 @@ func (m model) View() string {
 	header := m.viewHeader()
 	left := m.viewLeft(leftInner, paneH)
 	right := m.viewRight(rightInner, paneH)
+	if m.mode == modeLoadPreset {
+		right = m.viewPresets(rightInner, paneH)
+	}
 	footer := m.viewFooter()
 
 	panes := lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+	if m.mode == modeFilter || m.mode == modeReview {
+		panes = m.viewList(m.width-2, paneH)
+	}
 	return lipgloss.JoinVertical(lipgloss.Left, header, panes, footer)
 }
 
 func (m model) viewHeader() string {
 	title := styleTitle.Render("mrk brew")
+	if m.profile != "" {
+		title += styleFooter.Render("  profile: " + m.profile)
+	}
 	sel := styleCount.Render(fmt.Sprintf("%d selected", m.totalSelected()))
 	gap := m.width - lipgloss.Width(title) - lipgloss.Width(sel)
 	if gap < 1 {
@@ -480,7 +464,22 @@ func (m model) viewHeader() string {
 }
 
 func (m model) viewFooter() string {
-	return styleFooter.Render("↑↓/jk move · tab/hl switch pane · space toggle · a all · enter confirm · q quit")
+	switch m.mode {
+	case modeSavePreset:
+		return styleCatActive.Render("save preset as: ") + styleCatNorm.Render(m.input+"█") +
+			styleFooter.Render("  enter save · esc cancel")
+	case modeLoadPreset:
+		return styleFooter.Render("↑↓/jk move · enter load (replaces the selection) · esc back")
+	case modeFilter:
+		return styleFooter.Render("type to filter · ↑↓ move · space toggle · enter go to package · esc back")
+	case modeReview:
+		return styleFooter.Render("↑↓/jk move · space toggle · enter confirm · esc back")
+	}
+	footer := styleFooter.Render("↑↓/jk move · tab/hl switch pane · space toggle · a all · / filter · v selected · S save preset · p load preset · enter review · q quit")
+	if m.flash != "" {
+		footer = styleCount.Render(m.flash) + styleFooter.Render("  ·  enter review · q quit")
+	}
+	return footer
 }
 
 func (m model) viewLeft(inner, height int) string {
@@ -644,7 +643,41 @@ func main() {
 	installedCasksStr := flag.String("installed-casks", "", "Comma-separated installed casks")
 	skipFormulae := flag.Bool("skip-formulae", false, "Exclude formulae from picker")
 	skipCasks := flag.Bool("skip-casks", false, "Exclude casks from picker")
+	profile := flag.String("profile", brewfile.ActiveProfile(), `Only offer this profile's entries ("all" for every entry)`)
+	check := flag.Bool("check", false, "Report Brewfile packages without a description and exit")
+	updateDescs := flag.Bool("update-descs", false, "Cache brew's description of every Brewfile package and exit")
+	descCache := flag.String("desc-cache", descCachePath(), "Path to the description cache")
+	format := flag.String("format", outputFormats[0], "Output: "+strings.Join(outputFormats, ", "))
+	preselectFile := flag.String("preselect-file", "", "Start with the packages this selection file lists selected")
+	preset := flag.String("preset", "", "Start with the named preset from ~/.mrk/picker-presets selected")
+	saveSelection := flag.String("save-selection", "", "Also write the confirmed selection to this file")
+	nonInteractive := flag.Bool("non-interactive", false, "Print the preselection without opening the TUI")
 	flag.Parse()
+	*profile = strings.ToLower(*profile)
+	if !slices.Contains(outputFormats, *format) {
+		fmt.Fprintf(os.Stderr, "mrk-picker: unknown --format %q (want %s)\n", *format, strings.Join(outputFormats, ", "))
+		os.Exit(2)
+	}
+
+	doc, err := brewfile.Load(*brewfilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mrk-picker: %v\n", err)
+		os.Exit(1)
+	}
+	if *updateDescs {
+		if err := updateDescCache(doc, *descCache, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "mrk-picker: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	descs := loadDescCache(*descCache)
+	if *check {
+		if !checkDescs(doc, descs, os.Stdout, os.Stderr) {
+			os.Exit(1)
+		}
+		return
+	}
 
 	installedFormulae := map[string]bool{}
 	installedCasks := map[string]bool{}
@@ -659,16 +692,40 @@ func main() {
 		}
 	}
 
-	cats, err := parseBrewfile(*brewfilePath, installedFormulae, installedCasks, *skipFormulae, *skipCasks)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "mrk-picker: %v\n", err)
-		os.Exit(1)
-	}
+	cats := parseBrewfile(doc, installedFormulae, installedCasks, *skipFormulae, *skipCasks, *profile, descs)
 	if len(cats) == 0 {
 		fmt.Fprintln(os.Stderr, "mrk-picker: no packages found in Brewfile")
 		os.Exit(1)
 	}
 
+	var preselect []string
+	if *preselectFile != "" {
+		preselect = append(preselect, *preselectFile)
+	}
+	if *preset != "" {
+		path, err := presetPath(defaultPresetDir(), *preset)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mrk-picker: %v\n", err)
+			os.Exit(2)
+		}
+		preselect = append(preselect, path)
+	}
+	if err := loadPreselection(cats, preselect); err != nil {
+		fmt.Fprintf(os.Stderr, "mrk-picker: %v\n", err)
+		os.Exit(1)
+	}
+	if *nonInteractive {
+		if len(preselect) == 0 {
+			fmt.Fprintln(os.Stderr, "mrk-picker: --non-interactive needs --preset or --preselect-file")
+			os.Exit(2)
+		}
+		if err := emit(os.Stdout, *format, cats, doc, *saveSelection); err != nil {
+			fmt.Fprintf(os.Stderr, "mrk-picker: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Open /dev/tty explicitly so the TUI renders correctly even when
 	// stdout is captured by a shell subshell ($(...)).
 	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
@@ -678,7 +735,9 @@ func main() {
 	}
 	defer tty.Close()
 
-	p := tea.NewProgram(newModel(cats), tea.WithAltScreen(), tea.WithInput(tty), tea.WithOutput(tty))
+	m := newModel(cats)
+	m.profile = *profile
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithInput(tty), tea.WithOutput(tty))
 	final, err := p.Run()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "mrk-picker: %v\n", err)
@@ -690,12 +749,19 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Output selected packages as "type:name" lines
-	for _, cat := range result.cats {
-		for _, p := range cat.pkgs {
-			if p.selected {
-				fmt.Printf("%s:%s\n", p.kind, p.name)
-			}
+	if err := emit(os.Stdout, *format, result.cats, doc, *saveSelection); err != nil {
+		fmt.Fprintf(os.Stderr, "mrk-picker: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// emit prints the selection to w and, when asked, saves it as a selection
+// file.
+func emit(w io.Writer, format string, cats []category, doc *brewfile.File, savePath string) error {
+	if savePath != "" {
+		if err := writeSelectionFile(savePath, cats); err != nil {
+			return err
 		}
 	}
+	return writeSelection(w, format, cats, doc)
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	brewfile "mrk-brewfile"
+)
+
+// ── Output ────────────────────────────────────────────────────────────────
+
+// outputFormats lists the values --format accepts; the first is the default.
+var outputFormats = []string{"lines", "json", "brewfile", "nul"}
+
+// pkgJSON is one package in --format json.
+type pkgJSON struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Category  string `json:"category"`
+	Selected  bool   `json:"selected"`
+	Installed bool   `json:"installed"`
+	Line      string `json:"line"`
+}
+
+// writeSelection prints the result of a picker run:
+//
+//	lines     "formula:name" / "cask:name" per selected package
+//	nul       the same, each ended by NUL instead of a newline
+//	json      every package offered, with its category and selection
+//	brewfile  the selected entries' Brewfile lines under their section
+//	          headers, after the taps they need, for `brew bundle --file=-`
+func writeSelection(w io.Writer, format string, cats []category, doc *brewfile.File) error {
+	switch format {
+	case "lines", "nul":
+		end := "\n"
+		if format == "nul" {
+			end = "\x00"
+		}
+		for _, cat := range cats {
+			for _, p := range cat.pkgs {
+				if p.selected {
+					fmt.Fprintf(w, "%s:%s%s", p.kind, p.name, end)
+				}
+			}
+		}
+	case "json":
+		out := []pkgJSON{}
+		for _, cat := range cats {
+			for _, p := range cat.pkgs {
+				out = append(out, pkgJSON{Name: p.name, Kind: string(p.kind), Category: cat.name,
+					Selected: p.selected, Installed: p.installed, Line: strings.TrimSpace(p.line)})
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case "brewfile":
+		var body strings.Builder
+		var taps []string
+		for _, cat := range cats {
+			header := false
+			for _, p := range cat.pkgs {
+				if !p.selected {
+					continue
+				}
+				if !header {
+					fmt.Fprintf(&body, "\n## %s\n", cat.name)
+					header = true
+				}
+				body.WriteString(strings.TrimSpace(p.line) + "\n")
+				if i := strings.LastIndex(p.name, "/"); i > 0 {
+					if t := doc.Find(brewfile.KindTap, p.name[:i]); t != nil {
+						if line := strings.TrimSpace(doc.Lines[t.Line]); !slices.Contains(taps, line) {
+							taps = append(taps, line)
+						}
+					}
+				}
+			}
+		}
+		for _, t := range taps {
+			fmt.Fprintln(w, t)
+		}
+		text := body.String()
+		if len(taps) == 0 {
+			text = strings.TrimPrefix(text, "\n")
+		}
+		_, err := io.WriteString(w, text)
+		return err
+	default:
+		return fmt.Errorf("unknown format %q (want %s)", format, strings.Join(outputFormats, ", "))
+	}
+	return nil
+}
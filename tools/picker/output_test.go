@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteSelection(t *testing.T) {
+	doc, cats := fixture(t)
+	applySelection(cats, []string{"sevmorris/tap/bf", "cask:firefox"})
+	out := func(format string) string {
+		t.Helper()
+		var sb strings.Builder
+		if err := writeSelection(&sb, format, cats, doc); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		return sb.String()
+	}
+
+	if got, want := out("lines"), "formula:sevmorris/tap/bf\ncask:firefox\n"; got != want {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+	if got, want := out("nul"), "formula:sevmorris/tap/bf\x00cask:firefox\x00"; got != want {
+		t.Errorf("nul = %q, want %q", got, want)
+	}
+
+	// The tap a tap-qualified name needs comes first, then each section
+	// with something selected under its header.
+	want := `tap "sevmorris/tap"
+
+## CLI Tools
+brew "sevmorris/tap/bf"
+
+## Apps
+cask "firefox"
+`
+	if got := out("brewfile"); got != want {
+		t.Errorf("brewfile =\n%s\nwant\n%s", got, want)
+	}
+
+	var pkgs []pkgJSON
+	if err := json.Unmarshal([]byte(out("json")), &pkgs); err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 5 {
+		t.Fatalf("json lists %d packages, want every one offered", len(pkgs))
+	}
+	if p := pkgs[0]; p.Name != "git" || p.Kind != "formula" || p.Category != "CLI Tools" || !p.Installed || p.Selected || p.Line != `brew "git"` {
+		t.Errorf("json git = %+v", p)
+	}
+	if p := pkgs[3]; p.Name != "firefox" || p.Kind != "cask" || p.Category != "Apps" || !p.Selected {
+		t.Errorf("json firefox = %+v", p)
+	}
+
+	if err := writeSelection(&strings.Builder{}, "yaml", cats, doc); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestWriteSelectionBrewfileWithoutTaps(t *testing.T) {
+	doc, cats := fixture(t)
+	applySelection(cats, []string{"cask:docker"})
+	var sb strings.Builder
+	if err := writeSelection(&sb, "brewfile", cats, doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sb.String(), "## Apps\ncask \"docker\"\n"; got != want {
+		t.Errorf("brewfile = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	theme "mrk-theme"
+)
+
+// ── Presets ───────────────────────────────────────────────────────────────
+//
+// A selection file lists one package per line, as the default output format
+// writes them ("formula:name", "cask:name"); a bare name matches either kind
+// and "#" starts a comment. Named presets are selection files kept in
+// ~/.mrk/picker-presets/.
+
+// defaultPresetDir is where named presets live.
+func defaultPresetDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mrk", "picker-presets")
+}
+
+// presetPath returns the file for a named preset, refusing names that would
+// leave the preset directory.
+func presetPath(dir, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid preset name %q", name)
+	}
+	if dir == "" {
+		return "", errors.New("no home directory for presets")
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// listPresets returns the preset names in dir, sorted; a missing directory
+// has none.
+func listPresets(dir string) []string {
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range ents {
+		if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// readSelectionFile returns the items a selection file lists.
+func readSelectionFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var items []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			items = append(items, line)
+		}
+	}
+	return items, sc.Err()
+}
+
+// writeSelectionFile saves the selected packages in the default output
+// format, creating the directory it goes in.
+func writeSelectionFile(path string, cats []category) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var sb strings.Builder
+	sb.WriteString("# mrk-picker selection\n")
+	if err := writeSelection(&sb, "lines", cats, nil); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0o644)
+}
+
+// applySelection selects the packages items name. Installed packages stay
+// unselected, as in the TUI. It returns the items that match no package
+// offered.
+func applySelection(cats []category, items []string) (unknown []string) {
+	for _, item := range items {
+		kind, name, qualified := strings.Cut(item, ":")
+		if !qualified {
+			name = kind
+		}
+		found := false
+		for _, cat := range cats {
+			for _, p := range cat.pkgs {
+				if p.name == name && (!qualified || string(p.kind) == kind) {
+					found = true
+					if !p.installed {
+						p.selected = true
+					}
+				}
+			}
+		}
+		if !found {
+			unknown = append(unknown, item)
+		}
+	}
+	return unknown
+}
+
+// loadPreselection applies each selection file in turn, warning about
+// entries the Brewfile does not offer.
+func loadPreselection(cats []category, paths []string) error {
+	for _, path := range paths {
+		items, err := readSelectionFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("no selection file %s", path)
+		} else if err != nil {
+			return err
+		}
+		if unknown := applySelection(cats, items); len(unknown) > 0 {
+			fmt.Fprintf(os.Stderr, "mrk-picker: %s: not offered here (%d): %s\n", filepath.Base(path), len(unknown), strings.Join(unknown, ", "))
+		}
+	}
+	return nil
+}
+
+// ── Preset prompts ────────────────────────────────────────────────────────
+
+func (m model) handlePresetKey(msg tea.KeyMsg) (model, tea.Cmd) {
+	key := msg.String()
+	if key == "ctrl+c" {
+		m.cancelled = true
+		return m, tea.Quit
+	}
+	switch m.mode {
+	case modeSavePreset:
+		switch key {
+		case "esc":
+			m.mode = modeNormal
+		case "enter":
+			path, err := presetPath(m.presetDir, m.input)
+			if err == nil {
+				err = writeSelectionFile(path, m.cats)
+			}
+			if err != nil {
+				m.flash = "save failed: " + err.Error()
+			} else {
+				m.flash = fmt.Sprintf("saved %d package(s) as %q", m.totalSelected(), strings.TrimSpace(m.input))
+			}
+			m.mode = modeNormal
+		case "backspace":
+			if r := []rune(m.input); len(r) > 0 {
+				m.input = string(r[:len(r)-1])
+			}
+		default:
+			if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+				m.input += string(msg.Runes)
+			}
+		}
+	case modeLoadPreset:
+		switch key {
+		case "esc", "q":
+			m.mode = modeNormal
+		case "up", "k":
+			if m.presetIdx > 0 {
+				m.presetIdx--
+			}
+		case "down", "j":
+			if m.presetIdx < len(m.presets)-1 {
+				m.presetIdx++
+			}
+		case "enter":
+			name := m.presets[m.presetIdx]
+			items, err := readSelectionFile(filepath.Join(m.presetDir, name))
+			if err != nil {
+				m.flash = "load failed: " + err.Error()
+				m.mode = modeNormal
+				break
+			}
+			// A preset replaces the selection rather than adding to it.
+			for _, cat := range m.cats {
+				for _, p := range cat.pkgs {
+					p.selected = false
+				}
+			}
+			unknown := applySelection(m.cats, items)
+			m.flash = fmt.Sprintf("loaded %q: %d selected", name, m.totalSelected())
+			if len(unknown) > 0 {
+				m.flash += fmt.Sprintf(", %d not offered here", len(unknown))
+			}
+			m.mode = modeNormal
+		}
+	}
+	return m, nil
+}
+
+// startPresetLoad opens the preset list, or explains why there is none.
+func (m model) startPresetLoad() model {
+	m.presets = listPresets(m.presetDir)
+	if len(m.presets) == 0 {
+		m.flash = "no presets in ~/.mrk/picker-presets — press S to save one"
+		return m
+	}
+	m.presetIdx = 0
+	m.mode = modeLoadPreset
+	return m
+}
+
+func (m model) viewPresets(inner, height int) string {
+	var sb strings.Builder
+	sb.WriteString(styleCatActive.Render("Load preset") + "\n\n")
+	start := max(0, m.presetIdx-(height-2)+1)
+	for i, name := range m.presets[start:min(len(m.presets), start+max(height-2, 1))] {
+		i += start
+		name = theme.Truncate(name, max(1, inner-2))
+		if i == m.presetIdx {
+			sb.WriteString(stylePkgCurs.Render("▸ "+name) + "\n")
+		} else {
+			sb.WriteString(styleCatNorm.Render("  "+name) + "\n")
+		}
+	}
+	return stylePaneOn.Width(inner).Height(height).Render(strings.TrimRight(sb.String(), "\n"))
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	brewfile "mrk-brewfile"
+)
+
+const pickerFixture = `## Taps
+tap "sevmorris/tap"
+
+## CLI Tools
+# desc: Version control
+brew "git"
+brew "docker"
+brew "sevmorris/tap/bf"
+
+## Apps
+# desc: Web browser
+cask "firefox"
+cask "docker"
+`
+
+// fixture parses pickerFixture with git installed.
+func fixture(t *testing.T) (*brewfile.File, []category) {
+	t.Helper()
+	doc := brewfile.Parse([]byte(pickerFixture))
+	cats := parseBrewfile(doc, map[string]bool{"git": true}, nil, false, false, brewfile.AllProfiles, nil)
+	if len(cats) != 2 {
+		t.Fatalf("parsed %d categories", len(cats))
+	}
+	return doc, cats
+}
+
+func keyMsg(k string) tea.KeyMsg {
+	switch k {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case " ":
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(k)}
+	case "backspace":
+		return tea.KeyMsg{Type: tea.KeyBackspace}
+	case "up":
+		return tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+// press feeds keys to the model the way Bubble Tea would.
+func press(m model, keys ...string) model {
+	for _, k := range keys {
+		next, _ := m.Update(keyMsg(k))
+		m = next.(model)
+	}
+	return m
+}
+
+// selected lists the selected packages as the default output names them.
+func selected(cats []category) []string {
+	var out []string
+	for _, c := range cats {
+		for _, p := range c.pkgs {
+			if p.selected {
+				out = append(out, string(p.kind)+":"+p.name)
+			}
+		}
+	}
+	return out
+}
+
+func TestApplySelection(t *testing.T) {
+	cases := []struct {
+		items   []string
+		want    []string
+		unknown []string
+	}{
+		{[]string{"docker"}, []string{"formula:docker", "cask:docker"}, nil},
+		{[]string{"cask:docker"}, []string{"cask:docker"}, nil},
+		{[]string{"formula:firefox", "firefox"}, []string{"cask:firefox"}, []string{"formula:firefox"}},
+		{[]string{"git"}, nil, nil}, // installed: offered, but stays unselected
+		{[]string{"sevmorris/tap/bf", "nope"}, []string{"formula:sevmorris/tap/bf"}, []string{"nope"}},
+	}
+	for _, tc := range cases {
+		_, cats := fixture(t)
+		unknown := applySelection(cats, tc.items)
+		if got := selected(cats); !slices.Equal(got, tc.want) || !slices.Equal(unknown, tc.unknown) {
+			t.Errorf("applySelection(%q): selected %q, unknown %q; want %q, %q", tc.items, got, unknown, tc.want, tc.unknown)
+		}
+	}
+}
+
+func TestPresetPath(t *testing.T) {
+	if got, err := presetPath("/p", " studio "); err != nil || got != filepath.Join("/p", "studio") {
+		t.Errorf("presetPath(studio) = %q, %v", got, err)
+	}
+	for _, name := range []string{"", "  ", "../x", "a/b", `a\b`, ".hidden"} {
+		if got, err := presetPath("/p", name); err == nil {
+			t.Errorf("presetPath(%q) = %q, want an error", name, got)
+		}
+	}
+	if _, err := presetPath("", "studio"); err == nil {
+		t.Error("presetPath with no directory succeeded")
+	}
+}
+
+func TestReadSelectionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sel")
+	text := "# saved by hand\nformula:git\n\n  cask:firefox  # the browser\n#cask:docker\ndocker\n"
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	items, err := readSelectionFile(path)
+	if want := []string{"formula:git", "cask:firefox", "docker"}; err != nil || !slices.Equal(items, want) {
+		t.Errorf("items = %q, %v; want %q", items, err, want)
+	}
+
+	// What writeSelectionFile saves reads back as the same selection.
+	_, cats := fixture(t)
+	applySelection(cats, []string{"cask:docker", "firefox"})
+	saved := filepath.Join(t.TempDir(), "presets", "apps")
+	if err := writeSelectionFile(saved, cats); err != nil {
+		t.Fatal(err)
+	}
+	items, err = readSelectionFile(saved)
+	if want := []string{"cask:firefox", "cask:docker"}; err != nil || !slices.Equal(items, want) {
+		t.Errorf("saved items = %q, %v; want %q", items, err, want)
+	}
+}
+
+// The --non-interactive path: preselect from a file, then print and save
+// the selection without a terminal.
+func TestNonInteractiveSelection(t *testing.T) {
+	doc, cats := fixture(t)
+	dir := t.TempDir()
+	sel := filepath.Join(dir, "sel")
+	if err := os.WriteFile(sel, []byte("docker\ncask:firefox\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadPreselection(cats, []string{sel}); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	saved := filepath.Join(dir, "out")
+	if err := emit(&out, "lines", cats, doc, saved); err != nil {
+		t.Fatal(err)
+	}
+	if want := "formula:docker\ncask:firefox\ncask:docker\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if items, _ := readSelectionFile(saved); len(items) != 3 {
+		t.Errorf("saved selection = %q", items)
+	}
+
+	if err := loadPreselection(cats, []string{filepath.Join(dir, "missing")}); err == nil || !strings.Contains(err.Error(), "no selection file") {
+		t.Errorf("missing file: err = %v", err)
+	}
+}
+
+func TestLoadPresetReplacesSelection(t *testing.T) {
+	_, cats := fixture(t)
+	m := newModel(cats)
+	m.presetDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(m.presetDir, "apps"), []byte("cask:firefox\nnope\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	applySelection(cats, []string{"formula:docker"})
+
+	m = press(m, "p")
+	if m.mode != modeLoadPreset || !slices.Equal(m.presets, []string{"apps"}) {
+		t.Fatalf("p: mode=%v presets=%q flash=%q", m.mode, m.presets, m.flash)
+	}
+	m = press(m, "enter")
+	if got := selected(m.cats); !slices.Equal(got, []string{"cask:firefox"}) {
+		t.Errorf("selection after load = %q", got)
+	}
+	if m.flash != `loaded "apps": 1 selected, 1 not offered here` {
+		t.Errorf("flash = %q", m.flash)
+	}
+}